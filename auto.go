@@ -18,6 +18,14 @@ func (ab autoBundle) Open(path string) (io.ReadCloser, error) {
 	return bundle.Open(path)
 }
 
+func (ab autoBundle) wrapped() Bundle {
+	bundle, err := ab()
+	if err != nil {
+		return nil
+	}
+	return bundle
+}
+
 func (ab autoBundle) Close() error {
 	bundle, err := ab()
 	if err != nil {
@@ -0,0 +1,302 @@
+package resources
+
+import (
+	"bytes"
+	"container/list"
+	"io"
+	"io/ioutil"
+	"sync"
+)
+
+// DefaultCacheSize is the MaxBytes of a Cache if none is given.
+const DefaultCacheSize = 32 << 20
+
+// CacheOptions configures a Cache.
+type CacheOptions struct {
+	// MaxBytes bounds the total size of the cached contents. The
+	// least recently used contents are evicted to stay within it.
+	// If zero, DefaultCacheSize is used.
+	MaxBytes int64
+
+	// MaxEntrySize is the size of the largest resource which is
+	// cached. Larger resources are read from the wrapped bundle each
+	// time. If zero, it is MaxBytes.
+	MaxEntrySize int64
+}
+
+// CacheStats are counters of a Cache's activity.
+type CacheStats struct {
+	Hits      int64 // opens served from the cache
+	Misses    int64 // opens read from the wrapped bundle
+	Evictions int64 // entries removed to make room
+	Entries   int   // entries in the cache
+	Bytes     int64 // size of the cached contents
+}
+
+type cacheEntry struct {
+	path string
+	data []byte
+}
+
+// cacheCall is an in-flight read of a missed resource, which
+// concurrent opens of the same path wait for.
+type cacheCall struct {
+	done chan struct{}
+	data []byte // nil if the resource wasn't cached
+	err  error
+}
+
+// A Cache is a bundle which keeps the contents of the resources of
+// another bundle in memory once they have been read, for bundles whose
+// resources are expensive to read, eg: deflated zip entries or
+// resources fetched over HTTP.
+//
+// Concurrent opens of a resource which isn't cached read it from the
+// wrapped bundle once. Cached contents are used until they are evicted
+// or invalidated, so a Cache should be invalidated when the resources
+// it wraps change, eg: with InvalidateOn.
+//
+// A Cache implements Searcher and Lister, which find nothing if the
+// wrapped bundle doesn't implement them. Resources found through it
+// also read their contents through the cache. Closing it closes the
+// wrapped bundle.
+type Cache struct {
+	bundle Bundle
+	opts   CacheOptions
+
+	mu      sync.Mutex
+	lru     *list.List // of *cacheEntry, most recently used first
+	entries map[string]*list.Element
+	calls   map[string]*cacheCall
+	gen     uint64 // incremented by every invalidation
+	stats   CacheStats
+}
+
+// NewCache returns a Cache of the resources of b.
+func NewCache(b Bundle, opts CacheOptions) *Cache {
+	if opts.MaxBytes <= 0 {
+		opts.MaxBytes = DefaultCacheSize
+	}
+	if opts.MaxEntrySize <= 0 || opts.MaxEntrySize > opts.MaxBytes {
+		opts.MaxEntrySize = opts.MaxBytes
+	}
+	return &Cache{
+		bundle:  b,
+		opts:    opts,
+		lru:     list.New(),
+		entries: make(map[string]*list.Element),
+		calls:   make(map[string]*cacheCall),
+	}
+}
+
+// Stats returns the cache's counters.
+func (c *Cache) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stats
+}
+
+// Invalidate removes the contents of the resources at paths from the
+// cache. Reads which are in progress aren't cached when they finish.
+func (c *Cache) Invalidate(paths ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gen++
+	for _, path := range paths {
+		if elem, ok := c.entries[path]; ok {
+			c.remove(elem)
+		}
+	}
+}
+
+// InvalidateAll empties the cache.
+func (c *Cache) InvalidateAll() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gen++
+	c.lru.Init()
+	c.entries = make(map[string]*list.Element)
+	c.stats.Entries, c.stats.Bytes = 0, 0
+}
+
+// InvalidateOn invalidates the resources which w reports as changed,
+// until cancel is called.
+func (c *Cache) InvalidateOn(w Watcher) (cancel func()) {
+	return w.Watch(func(path string) {
+		c.Invalidate(path)
+	})
+}
+
+// remove removes an entry, with c.mu held.
+func (c *Cache) remove(elem *list.Element) {
+	entry := c.lru.Remove(elem).(*cacheEntry)
+	delete(c.entries, entry.path)
+	c.stats.Entries--
+	c.stats.Bytes -= int64(len(entry.data))
+}
+
+// add caches data for path, evicting entries to make room, with c.mu
+// held.
+func (c *Cache) add(path string, data []byte) {
+	if elem, ok := c.entries[path]; ok {
+		c.remove(elem)
+	}
+	for c.stats.Bytes+int64(len(data)) > c.opts.MaxBytes && c.lru.Len() > 0 {
+		c.remove(c.lru.Back())
+		c.stats.Evictions++
+	}
+	c.entries[path] = c.lru.PushFront(&cacheEntry{path, data})
+	c.stats.Entries++
+	c.stats.Bytes += int64(len(data))
+}
+
+func cachedReader(data []byte) io.ReadCloser {
+	return ioutil.NopCloser(bytes.NewReader(data))
+}
+
+// open returns the contents of the resource at path from the cache,
+// or reads them with open_fn.
+func (c *Cache) open(path string, open_fn func() (io.ReadCloser, error)) (io.ReadCloser, error) {
+	c.mu.Lock()
+	if elem, ok := c.entries[path]; ok {
+		c.lru.MoveToFront(elem)
+		c.stats.Hits++
+		c.mu.Unlock()
+		return cachedReader(elem.Value.(*cacheEntry).data), nil
+	}
+	c.stats.Misses++
+	if call, ok := c.calls[path]; ok {
+		c.mu.Unlock()
+		<-call.done
+		if call.err != nil {
+			return nil, call.err
+		}
+		if call.data != nil {
+			return cachedReader(call.data), nil
+		}
+		// Too large to share, so read it again.
+		return open_fn()
+	}
+	call := &cacheCall{done: make(chan struct{})}
+	c.calls[path] = call
+	gen := c.gen
+	c.mu.Unlock()
+
+	rdr, data, err := c.load(open_fn)
+
+	c.mu.Lock()
+	delete(c.calls, path)
+	call.data, call.err = data, err
+	if data != nil && gen == c.gen {
+		c.add(path, data)
+	}
+	c.mu.Unlock()
+	close(call.done)
+
+	if err != nil {
+		return nil, err
+	} else if data != nil {
+		return cachedReader(data), nil
+	}
+	return rdr, nil
+}
+
+// load reads a resource with open_fn. If it is small enough to cache,
+// its contents are returned, otherwise a reader of the whole resource
+// is.
+func (c *Cache) load(open_fn func() (io.ReadCloser, error)) (io.ReadCloser, []byte, error) {
+	rdr, err := open_fn()
+	if err != nil {
+		return nil, nil, err
+	}
+	data, err := ioutil.ReadAll(io.LimitReader(rdr, c.opts.MaxEntrySize+1))
+	if err != nil {
+		rdr.Close()
+		return nil, nil, err
+	}
+	if int64(len(data)) > c.opts.MaxEntrySize {
+		return &multiReadCloser{io.MultiReader(bytes.NewReader(data), rdr), rdr}, nil, nil
+	}
+	if err := rdr.Close(); err != nil {
+		return nil, nil, err
+	}
+	if data == nil {
+		data = []byte{}
+	}
+	return nil, data, nil
+}
+
+// multiReadCloser reads the start of a resource which has already
+// been read, followed by the rest of it.
+type multiReadCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// cacheResource is a resource whose contents are read through a Cache.
+type cacheResource struct {
+	Resource
+	cache *Cache
+}
+
+func (cr *cacheResource) Open() (io.ReadCloser, error) {
+	return cr.cache.open(cr.Path(), cr.Resource.Open)
+}
+
+func (c *Cache) wrap(rsrcs []Resource) []Resource {
+	wrapped := make([]Resource, len(rsrcs))
+	for i, rsrc := range rsrcs {
+		wrapped[i] = &cacheResource{rsrc, c}
+	}
+	return wrapped
+}
+
+func (c *Cache) wrapped() Bundle {
+	return c.bundle
+}
+
+func (c *Cache) Close() error {
+	return c.bundle.Close()
+}
+
+func (c *Cache) Open(path string) (io.ReadCloser, error) {
+	return c.open(path, func() (io.ReadCloser, error) {
+		return c.bundle.Open(path)
+	})
+}
+
+func (c *Cache) Find(path string) (Resource, error) {
+	searcher, ok := c.bundle.(Searcher)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	rsrc, err := searcher.Find(path)
+	if err != nil {
+		return nil, err
+	}
+	return &cacheResource{rsrc, c}, nil
+}
+
+func (c *Cache) Glob(pattern string) ([]Resource, error) {
+	searcher, ok := c.bundle.(Searcher)
+	if !ok {
+		return nil, nil
+	}
+	rsrcs, err := searcher.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+	return c.wrap(rsrcs), nil
+}
+
+func (c *Cache) List() ([]Resource, error) {
+	lister, ok := c.bundle.(Lister)
+	if !ok {
+		return nil, nil
+	}
+	rsrcs, err := lister.List()
+	if err != nil {
+		return nil, err
+	}
+	return c.wrap(rsrcs), nil
+}
@@ -0,0 +1,223 @@
+package resources
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	. "testing"
+	"time"
+)
+
+var Cache_Is_A_Bundle Bundle = &Cache{}
+var Cache_Is_A_Searcher Searcher = &Cache{}
+var Cache_Is_A_Lister Lister = &Cache{}
+
+// countBundle is a Bundle of in-memory contents, which counts opens.
+type countBundle struct {
+	contents map[string]string
+	opens    int32
+	delay    time.Duration
+}
+
+func (cb *countBundle) Open(path string) (io.ReadCloser, error) {
+	atomic.AddInt32(&cb.opens, 1)
+	time.Sleep(cb.delay)
+	data, ok := cb.contents[path]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return ioutil.NopCloser(strings.NewReader(data)), nil
+}
+
+func (cb *countBundle) Close() error {
+	return nil
+}
+
+func TestCache(t *T) {
+	cb := &countBundle{contents: map[string]string{
+		"a":   "aaaa",
+		"b":   "bbbb",
+		"c":   "cccc",
+		"big": strings.Repeat("x", 6),
+	}}
+	c := NewCache(cb, CacheOptions{MaxBytes: 8, MaxEntrySize: 5})
+
+	for i := 0; i < 3; i++ {
+		if got := readAll(t, c, "a"); got != "aaaa" {
+			t.Errorf("Open(a) = %q", got)
+		}
+	}
+	if cb.opens != 1 {
+		t.Errorf("a opened %d times, want 1", cb.opens)
+	}
+
+	readAll(t, c, "b")
+	readAll(t, c, "a") // a is now the most recently used
+	readAll(t, c, "c") // evicts b
+	stats := c.Stats()
+	t.Logf("%+v", stats)
+	if stats.Entries != 2 || stats.Bytes != 8 || stats.Evictions != 1 {
+		t.Errorf("Stats() = %+v, want 2 entries, 8 bytes, 1 eviction", stats)
+	}
+	cb.opens = 0
+	readAll(t, c, "a")
+	readAll(t, c, "b")
+	if cb.opens != 1 {
+		t.Errorf("%d opens after eviction, want 1 (b)", cb.opens)
+	}
+
+	cb.opens = 0
+	for i := 0; i < 2; i++ {
+		if got := readAll(t, c, "big"); got != cb.contents["big"] {
+			t.Errorf("Open(big) = %q", got)
+		}
+	}
+	if cb.opens != 2 {
+		t.Errorf("big opened %d times, want 2 (too large to cache)", cb.opens)
+	}
+
+	cb.contents["a"] = "AAAA"
+	c.Invalidate("a")
+	if got := readAll(t, c, "a"); got != "AAAA" {
+		t.Errorf("Open(a) after Invalidate = %q", got)
+	}
+	c.InvalidateAll()
+	if stats := c.Stats(); stats.Entries != 0 || stats.Bytes != 0 {
+		t.Errorf("Stats() after InvalidateAll = %+v", stats)
+	}
+
+	if _, err := c.Open("missing"); err != ErrNotFound {
+		t.Errorf("Open(missing) = %v, want ErrNotFound", err)
+	}
+}
+
+func TestCacheConcurrentMiss(t *T) {
+	cb := &countBundle{contents: map[string]string{"a": "aaaa"}, delay: 50 * time.Millisecond}
+	c := NewCache(cb, CacheOptions{})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rdr, err := c.Open("a")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			defer rdr.Close()
+			if data, _ := ioutil.ReadAll(rdr); string(data) != "aaaa" {
+				t.Errorf("Open(a) = %q", data)
+			}
+		}()
+	}
+	wg.Wait()
+	if cb.opens != 1 {
+		t.Errorf("a opened %d times, want 1", cb.opens)
+	}
+}
+
+func TestCacheInvalidateOn(t *T) {
+	zr := CreateTestZip(t)
+	zb, err := OpenZipReader(zr, int64(zr.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewCache(zb, CacheOptions{})
+	readAll(t, c, "foo.txt")
+	readAll(t, c, "foo.txt")
+	if stats := c.Stats(); stats.Hits != 1 || stats.Misses != 1 {
+		t.Errorf("Stats() = %+v, want 1 hit, 1 miss", stats)
+	}
+
+	w := &funcWatcher{}
+	cancel := c.InvalidateOn(w)
+	w.fn("foo.txt")
+	if stats := c.Stats(); stats.Entries != 0 {
+		t.Errorf("Stats() after change = %+v, want no entries", stats)
+	}
+	cancel()
+	if w.fn != nil {
+		t.Error("watch not cancelled")
+	}
+}
+
+// funcWatcher is a Watcher which reports the changes it's told to.
+type funcWatcher struct {
+	fn func(string)
+}
+
+func (fw *funcWatcher) Watch(fn func(string)) func() {
+	fw.fn = fn
+	return func() { fw.fn = nil }
+}
+
+func TestPoller(t *T) {
+	dir := t.TempDir()
+	write := func(name, data string) {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("a", "a")
+	write("b", "b")
+	p := NewPoller(OpenDir(dir).(Lister), time.Hour)
+
+	changed := make(chan string, 10)
+	cancel := p.Watch(func(path string) { changed <- path })
+	defer cancel()
+
+	write("a", "aa")
+	os.Remove(filepath.Join(dir, "b"))
+	write("c", "c")
+	p.Poll()
+
+	got := map[string]bool{}
+	for len(changed) > 0 {
+		got[<-changed] = true
+	}
+	t.Log(got)
+	if len(got) != 3 || !got["a"] || !got["b"] || !got["c"] {
+		t.Errorf("changed = %v, want a, b and c", got)
+	}
+}
+
+// failLister is a Lister which fails.
+type failLister struct{ err error }
+
+func (fl failLister) List() ([]Resource, error) { return nil, fl.err }
+
+func TestPollerErrors(t *T) {
+	errList := errors.New("list failed")
+	p := NewPoller(failLister{errList}, 0)
+	if p.interval != DefaultPollInterval {
+		t.Errorf("interval = %v, want DefaultPollInterval", p.interval)
+	}
+	p.interval = time.Millisecond
+	errs := make(chan error, 1)
+	p.OnError = func(err error) {
+		select {
+		case errs <- err:
+		default:
+		}
+	}
+	cancel := p.Watch(func(string) {})
+	defer cancel()
+
+	select {
+	case err := <-errs:
+		if err != errList {
+			t.Errorf("OnError(%v), want %v", err, errList)
+		}
+	case <-time.After(time.Second):
+		t.Error("OnError not called")
+	}
+	if p.Err() != errList {
+		t.Errorf("Err() = %v, want %v", p.Err(), errList)
+	}
+}
@@ -0,0 +1,113 @@
+/*
+Command resources-embed embeds a zip file of resources into a built
+executable, so it can be found by resources.OpenZip (and so the
+default search path).
+
+Usage:
+
+	resources-embed [-o output] executable source
+	resources-embed [-o output] -strip executable
+
+The source may be a directory, whose files are added to the zip file,
+or an existing zip file, whose contents are copied. Any zip file
+previously embedded in the executable is replaced. With -strip, the
+embedded zip file is removed instead.
+
+The zip file is appended to the executable, and its offsets adjusted
+to match, so there is no need to run "zip -A" afterwards. As that
+would invalidate a code signature, the signature is removed: sign the
+result afterwards, eg: with "codesign -s" on macOS or signtool on
+Windows. On macOS, the zip file is added to the __LINKEDIT segment,
+so the result can be signed.
+
+By default the executable is rewritten in place.
+*/
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/cookieo9/resources-go.v2"
+)
+
+var (
+	output = flag.String("o", "", "write the result to `file` instead of the executable")
+	strip  = flag.Bool("strip", false, "remove the embedded zip file")
+)
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: resources-embed [-o output] executable source")
+	fmt.Fprintln(os.Stderr, "       resources-embed [-o output] -strip executable")
+	flag.PrintDefaults()
+	os.Exit(2)
+}
+
+// embed writes the executable at exe_path to out_path, with the
+// zip file replaced by the contents of src_path, or removed if
+// src_path is empty.
+func embed(out_path, exe_path, src_path string) error {
+	exe, err := os.Open(exe_path)
+	if err != nil {
+		return err
+	}
+	defer exe.Close()
+	info, err := exe.Stat()
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file beside the output, so the
+	// executable can be rewritten in place.
+	tmp, err := ioutil.TempFile(filepath.Dir(out_path), ".resources-embed")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	if src_path == "" {
+		err = resources.StripZip(tmp, exe, info.Size())
+	} else {
+		var src resources.Bundle
+		if src, err = resources.OpenDirOrZip(src_path); err != nil {
+			return err
+		}
+		defer src.Close()
+		err = resources.EmbedZip(tmp, exe, info.Size(), src.(resources.Lister))
+	}
+	if err != nil {
+		return err
+	}
+	if err := tmp.Chmod(info.Mode()); err != nil {
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), out_path)
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("resources-embed: ")
+	flag.Usage = usage
+	flag.Parse()
+
+	if (*strip && flag.NArg() != 1) || (!*strip && flag.NArg() != 2) {
+		usage()
+	}
+	exe_path := flag.Arg(0)
+	out_path := *output
+	if out_path == "" {
+		out_path = exe_path
+	}
+
+	if err := embed(out_path, exe_path, flag.Arg(1)); err != nil {
+		log.Fatal(err)
+	}
+}
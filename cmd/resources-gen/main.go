@@ -0,0 +1,199 @@
+/*
+Command resources-gen compiles resources into a Go source file, for
+programs which can't have a zip file embedded after being built.
+
+Usage:
+
+	resources-gen [flags] source
+
+The source may be a directory or a zip file. The generated file
+declares a variable holding the resources as a resources.Bundle, which
+implements the Searcher and Lister interfaces just like a bundle
+opened with resources.OpenZip. The resources are stored compressed.
+
+It is intended to be run by go generate, eg:
+
+	//go:generate resources-gen -o assets_gen.go -exclude *.psd assets
+
+The output is deterministic: the same resources always produce the
+same file. The source is named in the generated file by its path
+relative to the output file.
+
+Flags:
+
+	-o file
+		write the generated code to file (default "resources_gen.go")
+	-pkg name
+		package name of the generated code (default $GOPACKAGE, as
+		set by go generate, or "main")
+	-var name
+		name of the variable holding the bundle (default "Resources")
+	-register name
+		register the bundle with resources.RegisterBundle, so it
+		can be used in search paths as "bundle:name"
+	-include pattern, -exclude pattern
+		only include, or exclude, resources matching the pattern;
+		see resources.PackOptions. May be repeated.
+*/
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"go/token"
+	"go/types"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/cookieo9/resources-go.v2"
+	"gopkg.in/cookieo9/resources-go.v2/internal/cmdflag"
+)
+
+var (
+	output   = flag.String("o", "resources_gen.go", "write the generated code to `file`")
+	pkg      = flag.String("pkg", "", "package `name` of the generated code")
+	variable = flag.String("var", "Resources", "`name` of the variable holding the bundle")
+	register = flag.String("register", "", "register the bundle with resources.RegisterBundle as `name`")
+	opts     = resources.PackOptions{Deterministic: true}
+)
+
+func init() {
+	flag.Var((*cmdflag.Strings)(&opts.Include), "include", "only include resources matching `pattern`")
+	flag.Var((*cmdflag.Strings)(&opts.Exclude), "exclude", "exclude resources matching `pattern`")
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: resources-gen [flags] source")
+	flag.PrintDefaults()
+	os.Exit(2)
+}
+
+// pack returns a zip file of the resources in the directory or zip
+// file at src_path.
+func pack(src_path string) ([]byte, error) {
+	src, err := resources.OpenDirOrZip(src_path)
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+
+	buf := new(bytes.Buffer)
+	zw := zip.NewWriter(buf)
+	if err := resources.Pack(zw, src.(resources.Lister), &opts); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// checkFlags reports flags which would produce invalid code. As well
+// as keywords, the variable can't be named after the imported package,
+// the functions the file or package must declare, or predeclared names.
+func checkFlags() error {
+	if !token.IsIdentifier(*pkg) || *pkg == "_" {
+		return fmt.Errorf("invalid package name %q", *pkg)
+	}
+	switch {
+	case !token.IsIdentifier(*variable) || *variable == "_":
+		return fmt.Errorf("invalid variable name %q", *variable)
+	case *variable == "resources" || *variable == "init" || *pkg == "main" && *variable == "main":
+		return fmt.Errorf("variable name %q is already used by the generated code", *variable)
+	case types.Universe.Lookup(*variable) != nil:
+		return fmt.Errorf("variable name %q is predeclared", *variable)
+	}
+	return nil
+}
+
+// relPath returns the path of src_path relative to the directory of
+// out_path, with forward slashes, so the generated code doesn't depend
+// on where it was generated.
+func relPath(out_path, src_path string) string {
+	out_abs, err := filepath.Abs(filepath.Dir(out_path))
+	if err != nil {
+		return filepath.Base(src_path)
+	}
+	src_abs, err := filepath.Abs(src_path)
+	if err != nil {
+		return filepath.Base(src_path)
+	}
+	rel, err := filepath.Rel(out_abs, src_abs)
+	if err != nil {
+		return filepath.Base(src_path)
+	}
+	return filepath.ToSlash(rel)
+}
+
+// generate returns the formatted Go source declaring the bundle, whose
+// source is named src_name.
+func generate(src_name string, data []byte) ([]byte, error) {
+	data_name := strings.ToLower((*variable)[:1]) + (*variable)[1:] + "Data"
+
+	buf := new(bytes.Buffer)
+	fmt.Fprintf(buf, "// Code generated by resources-gen from %s; DO NOT EDIT.\n\n", src_name)
+	fmt.Fprintf(buf, "package %s\n\n", *pkg)
+	fmt.Fprintf(buf, "import %q\n\n", "gopkg.in/cookieo9/resources-go.v2")
+	fmt.Fprintf(buf, "// %s holds the resources compiled from %s.\n", *variable, src_name)
+	fmt.Fprintf(buf, "var %s = resources.MustOpenZipData(%s)\n\n", *variable, data_name)
+	if *register != "" {
+		fmt.Fprintf(buf, "func init() {\n\tresources.RegisterBundle(%q, %s)\n}\n\n", *register, *variable)
+	}
+
+	fmt.Fprintf(buf, "const %s = \"\" +\n", data_name)
+	for len(data) > 0 {
+		n := 32
+		if n > len(data) {
+			n = len(data)
+		}
+		buf.WriteString("\t\"")
+		for _, b := range data[:n] {
+			fmt.Fprintf(buf, "\\x%02x", b)
+		}
+		data = data[n:]
+		if len(data) > 0 {
+			buf.WriteString("\" +\n")
+		} else {
+			buf.WriteString("\"\n")
+		}
+	}
+	return format.Source(buf.Bytes())
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("resources-gen: ")
+	flag.Usage = usage
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		usage()
+	}
+	if *pkg == "" {
+		*pkg = os.Getenv("GOPACKAGE")
+	}
+	if *pkg == "" {
+		*pkg = "main"
+	}
+	if err := checkFlags(); err != nil {
+		log.Fatal(err)
+	}
+
+	data, err := pack(flag.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+	src, err := generate(relPath(*output, flag.Arg(0)), data)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := ioutil.WriteFile(*output, src, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
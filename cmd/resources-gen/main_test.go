@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+	. "testing"
+)
+
+func TestCheckFlags(t *T) {
+	defer func(p, v string) { *pkg, *variable = p, v }(*pkg, *variable)
+
+	*pkg = "main"
+	for _, name := range []string{"", "1x", "a-b", "_", "var", "resources", "init", "main", "string", "nil"} {
+		*variable = name
+		if err := checkFlags(); err == nil {
+			t.Errorf("checkFlags() with -var %q succeeded", name)
+		}
+	}
+	*variable = "Resources"
+	if err := checkFlags(); err != nil {
+		t.Error("checkFlags():", err)
+	}
+	*pkg, *variable = "assets", "main"
+	if err := checkFlags(); err != nil {
+		t.Error("checkFlags() with -var main in another package:", err)
+	}
+	*pkg = "_"
+	if err := checkFlags(); err == nil {
+		t.Error("checkFlags() with -pkg _ succeeded")
+	}
+}
+
+func TestRelPath(t *T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "pkg", "assets_gen.go")
+	src := filepath.Join(dir, "pkg", "static", "assets")
+	if got := relPath(out, src); got != "static/assets" {
+		t.Errorf("relPath() = %q, want static/assets", got)
+	}
+}
+
+func TestGenerate(t *T) {
+	defer func(p, v, r string) { *pkg, *variable, *register = p, v, r }(*pkg, *variable, *register)
+	*pkg, *variable, *register = "assets", "Files", "assets"
+
+	dir := t.TempDir()
+	for name, data := range map[string]string{"a.txt": "a", "b/c.txt": "c"} {
+		file := filepath.Join(dir, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(file), 0755)
+		if err := os.WriteFile(file, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var srcs [][]byte
+	for i := 0; i < 2; i++ {
+		data, err := pack(dir)
+		if err != nil {
+			t.Fatal("pack():", err)
+		}
+		src, err := generate("static", data)
+		if err != nil {
+			t.Fatal("generate():", err)
+		}
+		srcs = append(srcs, src)
+	}
+	if !bytes.Equal(srcs[0], srcs[1]) {
+		t.Error("generated code isn't deterministic")
+	}
+
+	src := string(srcs[0])
+	if !strings.HasPrefix(src, "// Code generated by resources-gen from static; DO NOT EDIT.") {
+		t.Errorf("header = %q", strings.SplitN(src, "\n", 2)[0])
+	}
+	file, err := parser.ParseFile(token.NewFileSet(), "gen.go", src, 0)
+	if err != nil {
+		t.Fatal("generated code doesn't parse:", err)
+	}
+	if file.Name.Name != "assets" || file.Scope.Lookup("Files") == nil || file.Scope.Lookup("filesData") == nil {
+		t.Error("generated code doesn't declare package assets, Files and filesData")
+	}
+}
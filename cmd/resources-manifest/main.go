@@ -0,0 +1,155 @@
+/*
+Command resources-manifest writes a manifest of the SHA-256 hashes of
+a directory or zip file of resources, for use with
+resources.OpenVerified, and optionally signs it.
+
+Usage:
+
+	resources-manifest [-o file] [-key private-key] source
+	resources-manifest -genkey name
+
+The manifest is written to the file given by -o (default "MANIFEST"
+in the source directory, or standard output for zip files). If the
+manifest is written into the source directory, it is left out of
+itself, along with its signature.
+
+With -key, the manifest is signed with the ed25519 private key in the
+named file, and the signature written beside the manifest with a
+".sig" extension. Use resources.LoadManifest with the public key to
+check it.
+
+With -genkey, a new key pair is written to name (the private key) and
+name.pub (the public key), both base64 encoded.
+*/
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/cookieo9/resources-go.v2"
+)
+
+var (
+	output = flag.String("o", "", "write the manifest to `file`")
+	key    = flag.String("key", "", "sign the manifest with the private key in `file`")
+	genkey = flag.String("genkey", "", "generate a key pair, writing the private key to `name`")
+)
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: resources-manifest [-o file] [-key private-key] source")
+	fmt.Fprintln(os.Stderr, "       resources-manifest -genkey name")
+	flag.PrintDefaults()
+	os.Exit(2)
+}
+
+func generateKey(name string) error {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return err
+	}
+	enc := base64.StdEncoding
+	if err := ioutil.WriteFile(name, []byte(enc.EncodeToString(priv)+"\n"), 0600); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(name+".pub", []byte(enc.EncodeToString(pub)+"\n"), 0644)
+}
+
+func readKey(name string) (ed25519.PrivateKey, error) {
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	priv, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(priv) != ed25519.PrivateKeySize {
+		return nil, errors.New(name + ": not a base64 encoded ed25519 private key")
+	}
+	return ed25519.PrivateKey(priv), nil
+}
+
+// manifest builds the manifest of the resources at src_path, and
+// writes it to out_path, or standard output if out_path is empty.
+func manifest(src_path, out_path string) error {
+	src, err := resources.OpenDirOrZip(src_path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	m, err := resources.BuildManifest(src.(resources.Lister))
+	if err != nil {
+		return err
+	}
+	if out_path != "" {
+		// Leave the manifest and signature out of themselves.
+		if rel, err := filepath.Rel(src_path, out_path); err == nil {
+			delete(m.Hashes, filepath.ToSlash(rel))
+			delete(m.Hashes, filepath.ToSlash(rel)+".sig")
+		}
+	}
+	data, err := m.MarshalText()
+	if err != nil {
+		return err
+	}
+
+	var sig []byte
+	if *key != "" {
+		priv, err := readKey(*key)
+		if err != nil {
+			return err
+		}
+		sig = resources.SignManifest(data, priv)
+	}
+
+	if out_path == "" {
+		if sig != nil {
+			return errors.New("-o is required to write a signature")
+		}
+		_, err := os.Stdout.Write(data)
+		return err
+	}
+	if err := ioutil.WriteFile(out_path, data, 0644); err != nil {
+		return err
+	}
+	if sig != nil {
+		return ioutil.WriteFile(out_path+".sig", sig, 0644)
+	}
+	return nil
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("resources-manifest: ")
+	flag.Usage = usage
+	flag.Parse()
+
+	if *genkey != "" {
+		if flag.NArg() != 0 {
+			usage()
+		}
+		if err := generateKey(*genkey); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if flag.NArg() != 1 {
+		usage()
+	}
+	src_path, out_path := flag.Arg(0), *output
+	if info, err := os.Stat(src_path); err == nil && info.IsDir() && out_path == "" {
+		out_path = filepath.Join(src_path, "MANIFEST")
+	}
+	if err := manifest(src_path, out_path); err != nil {
+		log.Fatal(err)
+	}
+}
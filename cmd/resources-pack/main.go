@@ -0,0 +1,117 @@
+/*
+Command resources-pack writes a zip file of resources, optionally
+encrypting them.
+
+Usage:
+
+	resources-pack [flags] -o output.zip source
+
+The source may be a directory or a zip file.
+
+Flags:
+
+	-o file
+		write the zip file to file
+	-include pattern, -exclude pattern
+		only include, or exclude, resources matching the pattern;
+		see resources.PackOptions. May be repeated.
+	-deterministic
+		write the same zip file for the same resources, by removing
+		modification times
+	-key file
+		encrypt the resources with the key in file, for reading with
+		resources.OpenEncrypted; see resources.KeyFromFile
+	-key-env name
+		encrypt the resources with the base64 encoded key in the
+		environment variable name
+
+A new key can be made with:
+
+	(printf base64:; head -c 32 /dev/urandom | base64) > key
+*/
+package main
+
+import (
+	"archive/zip"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"gopkg.in/cookieo9/resources-go.v2"
+	"gopkg.in/cookieo9/resources-go.v2/internal/cmdflag"
+)
+
+var (
+	output  = flag.String("o", "", "write the zip file to `file`")
+	keyFile = flag.String("key", "", "encrypt with the key in `file`")
+	keyEnv  = flag.String("key-env", "", "encrypt with the key in the environment variable `name`")
+	opts    resources.PackOptions
+)
+
+func init() {
+	flag.Var((*cmdflag.Strings)(&opts.Include), "include", "only include resources matching `pattern`")
+	flag.Var((*cmdflag.Strings)(&opts.Exclude), "exclude", "exclude resources matching `pattern`")
+	flag.BoolVar(&opts.Deterministic, "deterministic", false, "remove modification times")
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: resources-pack [flags] -o output.zip source")
+	flag.PrintDefaults()
+	os.Exit(2)
+}
+
+// pack writes the resources at src_path to a zip file at out_path.
+func pack(out_path, src_path string) error {
+	src, err := resources.OpenDirOrZip(src_path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	out, err := os.Create(out_path)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	zw := zip.NewWriter(out)
+	if err := resources.Pack(zw, src.(resources.Lister), &opts); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	return out.Close()
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("resources-pack: ")
+	flag.Usage = usage
+	flag.Parse()
+
+	if flag.NArg() != 1 || *output == "" || (*keyFile != "" && *keyEnv != "") {
+		usage()
+	}
+
+	var keys resources.KeyProvider
+	switch {
+	case *keyFile != "":
+		keys = resources.KeyFromFile(*keyFile)
+	case *keyEnv != "":
+		keys = resources.KeyFromEnv(*keyEnv)
+	}
+	if keys != nil {
+		key, err := keys.Key()
+		if err != nil {
+			log.Fatal(err)
+		}
+		opts.Key = key
+	}
+
+	if err := pack(*output, flag.Arg(0)); err != nil {
+		os.Remove(*output)
+		log.Fatal(err)
+	}
+}
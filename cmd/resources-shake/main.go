@@ -0,0 +1,169 @@
+/*
+Command resources-shake writes a zip file of only the resources which
+were used, as recorded by resources.Recorder, and reports the rest.
+
+Usage:
+
+	resources-shake [flags] -o output.zip -r recording source
+
+The source may be a directory or a zip file. Recordings are made by
+wrapping the bundles of a program with a Recorder's Middleware, eg:
+
+	rec, err := resources.NewRecorder("used.txt")
+	...
+	bundle = rec.Middleware()(bundle)
+
+then running it, or its tests, so every resource it needs is used.
+
+Flags:
+
+	-o file
+		write the zip file to file
+	-r file
+		read a recording from file. May be repeated.
+	-keep pattern
+		also keep resources matching the pattern, as for the
+		-include flag of resources-pack. May be repeated.
+	-report file
+		write the report of dropped resources to file, instead of
+		standard error
+	-deterministic
+		write the same zip file for the same resources, by removing
+		modification times
+*/
+package main
+
+import (
+	"archive/zip"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+
+	"gopkg.in/cookieo9/resources-go.v2"
+	"gopkg.in/cookieo9/resources-go.v2/internal/cmdflag"
+	"gopkg.in/cookieo9/resources-go.v2/internal/match"
+)
+
+var (
+	output     = flag.String("o", "", "write the zip file to `file`")
+	reportFile = flag.String("report", "", "write the report to `file`")
+	recordings cmdflag.Strings
+	keep       cmdflag.Strings
+	opts       resources.PackOptions
+)
+
+func init() {
+	flag.Var(&recordings, "r", "read a recording from `file`")
+	flag.Var(&keep, "keep", "keep resources matching `pattern`")
+	flag.BoolVar(&opts.Deterministic, "deterministic", false, "remove modification times")
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: resources-shake [flags] -o output.zip -r recording source")
+	flag.PrintDefaults()
+	os.Exit(2)
+}
+
+// shake writes the used resources at src_path to a zip file at
+// out_path, and returns the paths and sizes of those dropped.
+func shake(out_path, src_path string, used map[string]bool) (map[string]int64, error) {
+	src, err := resources.OpenDirOrZip(src_path)
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+
+	dropped := make(map[string]int64)
+	list, err := src.(resources.Lister).List()
+	if err != nil {
+		return nil, err
+	}
+	for _, rsrc := range list {
+		info, err := rsrc.Stat()
+		if err != nil {
+			return nil, err
+		}
+		if info.IsDir() || used[rsrc.Path()] {
+			continue
+		}
+		if ok, err := match.Any(keep, rsrc.Path()); err != nil {
+			return nil, err
+		} else if !ok {
+			dropped[rsrc.Path()] = info.Size()
+		}
+	}
+
+	out, err := os.Create(out_path)
+	if err != nil {
+		return nil, err
+	}
+	defer out.Close()
+
+	opts.Filter = func(p string) bool {
+		_, drop := dropped[p]
+		return !drop
+	}
+	zw := zip.NewWriter(out)
+	if err := resources.Pack(zw, src.(resources.Lister), &opts); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return dropped, out.Close()
+}
+
+// report writes the dropped resources, largest first, and their total.
+func report(w io.Writer, dropped map[string]int64) {
+	paths := make([]string, 0, len(dropped))
+	var total int64
+	for p, size := range dropped {
+		paths = append(paths, p)
+		total += size
+	}
+	sort.Slice(paths, func(i, j int) bool {
+		if dropped[paths[i]] != dropped[paths[j]] {
+			return dropped[paths[i]] > dropped[paths[j]]
+		}
+		return paths[i] < paths[j]
+	})
+	for _, p := range paths {
+		fmt.Fprintf(w, "dropped %10d %s\n", dropped[p], p)
+	}
+	fmt.Fprintf(w, "dropped %d resources, %d bytes\n", len(paths), total)
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("resources-shake: ")
+	flag.Usage = usage
+	flag.Parse()
+
+	if flag.NArg() != 1 || *output == "" || len(recordings) == 0 {
+		usage()
+	}
+
+	used, err := resources.ReadRecordings(recordings...)
+	if err != nil {
+		log.Fatal(err)
+	}
+	dropped, err := shake(*output, flag.Arg(0), used)
+	if err != nil {
+		os.Remove(*output)
+		log.Fatal(err)
+	}
+
+	w := io.Writer(os.Stderr)
+	if *reportFile != "" {
+		file, err := os.Create(*reportFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer file.Close()
+		w = file
+	}
+	report(w, dropped)
+}
@@ -0,0 +1,347 @@
+package resources
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// PathEnv is the environment variable which, when set, replaces the
+// default search path. Its value is parsed with ParsePath.
+const PathEnv = "RESOURCES_PATH"
+
+// An Opener opens the Bundle named by the argument of a
+// "scheme:argument" search path element.
+type Opener func(arg string) (Bundle, error)
+
+var (
+	schemesMu sync.RWMutex
+
+	// schemes maps the scheme of a search path element to the
+	// Opener which handles its argument.
+	schemes = map[string]Opener{
+		"fs":     func(dir string) (Bundle, error) { return OpenFS(dir), nil },
+		"zip":    func(path string) (Bundle, error) { return OpenZip(path) },
+		"pkg":    OpenPackage,
+		"bundle": openRegistered,
+	}
+
+	// registered holds the bundles added with RegisterBundle.
+	registered = map[string]Bundle{}
+
+	// keywords are search path elements which stand alone and
+	// take no argument.
+	keywords = map[string]func() (Bundle, error){
+		"cwd":    func() (Bundle, error) { return OpenFS("."), nil },
+		"exe":    openExecutable,
+		"exedir": openExecutableDir,
+		"main":   OpenMainPackage,
+	}
+)
+
+// defaultPath is the search path used when PathEnv is not set.
+var defaultPath = []string{"exe", "cwd", "exedir", "main"}
+
+// RegisterScheme makes open handle search path elements of the form
+// "scheme:argument", replacing any existing handler for scheme.
+// It is typically called from the init function of a package
+// providing a new kind of Bundle.
+func RegisterScheme(scheme string, open Opener) {
+	schemesMu.Lock()
+	defer schemesMu.Unlock()
+	schemes[scheme] = open
+}
+
+// RegisterBundle makes bundle available as the search path element
+// "bundle:name", replacing any bundle already registered with that
+// name. Code generated by the resources-gen command uses it to
+// register compiled-in resources.
+func RegisterBundle(name string, bundle Bundle) {
+	schemesMu.Lock()
+	defer schemesMu.Unlock()
+	registered[name] = bundle
+}
+
+func openRegistered(name string) (Bundle, error) {
+	schemesMu.RLock()
+	defer schemesMu.RUnlock()
+	if bundle, ok := registered[name]; ok {
+		return bundle, nil
+	}
+	return nil, fmt.Errorf("resources: no bundle registered as %q", name)
+}
+
+func lookupScheme(scheme string) (Opener, bool) {
+	schemesMu.RLock()
+	defer schemesMu.RUnlock()
+	open, ok := schemes[scheme]
+	return open, ok
+}
+
+func openExecutable() (Bundle, error) {
+	exe_path, err := ExecutablePath()
+	if err != nil {
+		return nil, err
+	}
+	return OpenZip(exe_path)
+}
+
+func openExecutableDir() (Bundle, error) {
+	exe_path, err := ExecutablePath()
+	if err != nil {
+		return nil, err
+	}
+	return OpenFS(filepath.Dir(exe_path)), nil
+}
+
+// SplitPath splits a list of search path elements joined by the
+// OS-specific list separator (as in $PATH) into its elements.
+//
+// Where the list separator is a colon, the argument of a
+// "scheme:argument" element is also split off by the separator, so
+// such elements are rejoined with their argument. So are URLs, such
+// as "https://host:8080/x", including the port.
+func SplitPath(list string) []string {
+	parts := filepath.SplitList(list)
+	if os.PathListSeparator != ':' {
+		return parts
+	}
+
+	var elems []string
+	for i := 0; i < len(parts); i++ {
+		_, ok := lookupScheme(parts[i])
+		is_url := i+1 < len(parts) && strings.HasPrefix(parts[i+1], "//")
+		if (ok || is_url) && i+1 < len(parts) {
+			elem := parts[i] + ":" + parts[i+1]
+			i++
+			if is_url && i+1 < len(parts) && isPort(parts[i+1]) {
+				elem += ":" + parts[i+1]
+				i++
+			}
+			elems = append(elems, elem)
+			continue
+		}
+		elems = append(elems, parts[i])
+	}
+	return elems
+}
+
+// isPort reports whether s is the port of a URL split at its colon,
+// followed by the rest of the URL, eg: "8080/x".
+func isPort(s string) bool {
+	port, _, _ := strings.Cut(s, "/")
+	if port == "" {
+		return false
+	}
+	for _, c := range port {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// OpenPathElement opens the Bundle described by a single search path
+// element, which may be:
+//  - "cwd": the current working directory
+//  - "exe": the executable treated as a zip file
+//  - "exedir": the directory containing the executable
+//  - "main": the source directory of the main package
+//  - "fs:dir", "zip:file", or "pkg:importpath": the named directory,
+//    zip file, or package source directory
+//  - "bundle:name": a bundle added with RegisterBundle
+//  - "scheme:argument": a scheme added with RegisterScheme
+//  - anything else: a file system directory
+func OpenPathElement(elem string) (Bundle, error) {
+	if open, ok := keywords[elem]; ok {
+		return open()
+	}
+	if scheme, arg, ok := strings.Cut(elem, ":"); ok {
+		if open, ok := lookupScheme(scheme); ok {
+			return open(arg)
+		}
+	}
+	return OpenFS(elem), nil
+}
+
+// OpenPath opens each of the search path elements (see
+// OpenPathElement) and returns them as an OwningSequence. If any
+// element fails to open, the already opened bundles are closed and
+// the error is returned.
+func OpenPath(elems ...string) (OwningSequence, error) {
+	var seq OwningSequence
+	for _, elem := range elems {
+		bundle, err := OpenPathElement(elem)
+		if err != nil {
+			seq.Close()
+			return nil, err
+		}
+		seq = append(seq, bundle)
+	}
+	return seq, nil
+}
+
+var (
+	defaultMu     sync.Mutex // serializes loading and replacing
+	defaultLoaded atomic.Bool
+	defaultOwned  OwningSequence
+	defaultSearch SearchPath
+)
+
+// loadDefault builds the default search path, from PathEnv if set.
+// Unlike OpenPath, elements which can't be opened are skipped, as
+// a missing zip file in the executable is not an error.
+func loadDefault() OwningSequence {
+	elems := defaultPath
+	if list := os.Getenv(PathEnv); list != "" {
+		elems = SplitPath(list)
+	}
+
+	var seq OwningSequence
+	for _, elem := range elems {
+		if bundle, err := OpenPathElement(elem); err == nil {
+			seq = append(seq, bundle)
+		}
+	}
+	return seq
+}
+
+// DefaultPath returns the default search path. Unless replaced by
+// SetDefaultPath or the RESOURCES_PATH environment variable, it
+// contains:
+//  - The executable treated as a ZipBundle
+//  - The current working directory
+//  - The directory containing the executable
+//  - The source-code directory of the main package
+//
+// The search path is built on first use, rather than when the
+// package is initialized. It may be modified concurrently with its
+// use, eg: by tests overriding resources with WithOverride.
+func DefaultPath() *SearchPath {
+	if !defaultLoaded.Load() {
+		defaultMu.Lock()
+		if !defaultLoaded.Load() {
+			defaultOwned = loadDefault()
+			defaultSearch.Set(defaultOwned...)
+			defaultLoaded.Store(true)
+		}
+		defaultMu.Unlock()
+	}
+	return &defaultSearch
+}
+
+// DefaultBundle is searched by the package level Open, Find, Glob and
+// List functions. It initially contains only the default search path
+// (see DefaultPath), which is loaded when it is first searched.
+// Programs may add bundles to it before using it, but as it isn't safe
+// for concurrent modification, adding them to DefaultPath is
+// preferred.
+var DefaultBundle = BundleSequence{defaultBundle{}}
+
+// defaultBundle searches the default search path, loading it first if
+// needed.
+type defaultBundle struct{}
+
+func (defaultBundle) Open(path string) (io.ReadCloser, error) {
+	return DefaultPath().Open(path)
+}
+
+func (defaultBundle) Find(path string) (Resource, error) {
+	return DefaultPath().Find(path)
+}
+
+func (defaultBundle) FindAll(path string) ([]Resource, error) {
+	return DefaultPath().FindAll(path)
+}
+
+func (defaultBundle) Glob(pattern string) ([]Resource, error) {
+	return DefaultPath().Glob(pattern)
+}
+
+func (defaultBundle) List() ([]Resource, error) {
+	return DefaultPath().List()
+}
+
+func (defaultBundle) Bundles() []Bundle {
+	return DefaultPath().Bundles()
+}
+
+// Close is a no-op: the default search path is closed by CloseDefault.
+func (defaultBundle) Close() error {
+	return nil
+}
+
+// WithOverride() is a shortcut for DefaultPath().WithOverride()
+func WithOverride(bundle Bundle) (restore func()) {
+	return DefaultPath().WithOverride(bundle)
+}
+
+// SetDefaultPath replaces the default search path with the given
+// search path elements (see OpenPathElement), closing the bundles
+// opened for the previous one. Any bundles added to the default
+// search path are removed. If any element fails to open, the default
+// search path is left unchanged.
+//
+// The previous bundles are closed immediately, so snapshots of the
+// default search path taken before, and resources found in them, must
+// no longer be used.
+func SetDefaultPath(elems ...string) error {
+	seq, err := OpenPath(elems...)
+	if err != nil {
+		return err
+	}
+
+	defaultMu.Lock()
+	old := defaultOwned
+	defaultOwned = seq
+	defaultSearch.Set(seq...)
+	defaultLoaded.Store(true)
+	defaultMu.Unlock()
+	return old.Close()
+}
+
+// CloseDefault closes the bundles opened for the default search path,
+// such as the executable's zip file, and empties it. The next use of
+// the default search path rebuilds it. Errors are combined as in
+// OwningSequence.Close. As with SetDefaultPath, snapshots taken
+// before, and resources found in them, must no longer be used.
+func CloseDefault() error {
+	defaultMu.Lock()
+	old := defaultOwned
+	defaultOwned = nil
+	defaultSearch.Set()
+	defaultLoaded.Store(false)
+	defaultMu.Unlock()
+	return old.Close()
+}
+
+// ParsePath opens a list of search path elements joined by the
+// OS-specific list separator, as found in the RESOURCES_PATH
+// environment variable. See SplitPath and OpenPath.
+func ParsePath(list string) (OwningSequence, error) {
+	return OpenPath(SplitPath(list)...)
+}
+
+// Open() is a shortcut for DefaultBundle.Open()
+func Open(path string) (io.ReadCloser, error) {
+	return DefaultBundle.Open(path)
+}
+
+// Find() is a shortcut for DefaultBundle.Find()
+func Find(path string) (Resource, error) {
+	return DefaultBundle.Find(path)
+}
+
+// Glob() is a shortcut for DefaultBundle.Glob()
+func Glob(pattern string) ([]Resource, error) {
+	return DefaultBundle.Glob(pattern)
+}
+
+// List() is a shortcut for DefaultBundle.List()
+func List() ([]Resource, error) {
+	return DefaultBundle.List()
+}
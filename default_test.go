@@ -0,0 +1,55 @@
+package resources
+
+import (
+	"os"
+	"reflect"
+	. "testing"
+)
+
+func TestSplitPath(t *T) {
+	if os.PathListSeparator != ':' {
+		t.Skip("list separator is not a colon")
+	}
+
+	for _, test := range []struct {
+		list string
+		want []string
+	}{
+		{
+			"exe:cwd:zip:/a/b.zip:pkg:example.com/x:/some/dir:fs:rel",
+			[]string{"exe", "cwd", "zip:/a/b.zip", "pkg:example.com/x", "/some/dir", "fs:rel"},
+		},
+		{
+			"https://host:8080/x:cwd:http://other/y:https://host:443",
+			[]string{"https://host:8080/x", "cwd", "http://other/y", "https://host:443"},
+		},
+	} {
+		if got := SplitPath(test.list); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("SplitPath(%q) = %q, want %q", test.list, got, test.want)
+		}
+	}
+}
+
+func TestSetDefaultPath(t *T) {
+	defer CloseDefault()
+
+	if err := SetDefaultPath("fs:.", "cwd"); err != nil {
+		t.Fatal("SetDefaultPath():", err)
+	}
+	if n := len(DefaultPath().Snapshot()); n != 2 {
+		t.Errorf("len(DefaultPath().Snapshot()) = %d, want 2", n)
+	}
+	if _, err := Find("default_test.go"); err != nil {
+		t.Error("Find(default_test.go):", err)
+	}
+	if _, err := DefaultBundle.Find("default_test.go"); err != nil {
+		t.Error("DefaultBundle.Find(default_test.go):", err)
+	}
+
+	if err := SetDefaultPath("zip:does-not-exist.zip"); err == nil {
+		t.Error("SetDefaultPath(missing zip) succeeded")
+	}
+	if n := len(DefaultPath().Snapshot()); n != 2 {
+		t.Errorf("failed SetDefaultPath changed DefaultPath(), len = %d", n)
+	}
+}
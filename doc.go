@@ -9,6 +9,11 @@ Data can be loaded from a user-provided search path whose elements may be:
  - A zip file
  - A zip file embedded in the executable
 
+The default search path, used by the package level Open, Find, Glob
+and List functions, can be replaced by setting the RESOURCES_PATH
+environment variable to a list of search path elements, joined as in
+$PATH, or by calling SetDefaultPath.
+
 Source code can be found at https://github.com/cookieo9/resources-go
 */
 package resources
@@ -0,0 +1,288 @@
+package resources
+
+import (
+	"archive/zip"
+	"bytes"
+	"debug/elf"
+	"debug/macho"
+	"debug/pe"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// lcCodeSignature is the Mach-O load command of a code signature.
+const lcCodeSignature = 0x1d
+
+// machoPageSize is the page size __LINKEDIT segments are rounded up
+// to, that of darwin/arm64, which is a multiple of darwin/amd64's.
+const machoPageSize = 0x4000
+
+// An exeImage is how to write an executable without its signature, or
+// the zip file embedded in it.
+type exeImage struct {
+	header []byte // the start of the executable, with the headers changed to remove the signature
+	end    int64  // the end of the executable's own data, where an embedded zip file starts
+
+	// resize, if not nil, changes header for the executable, and the
+	// zip file which follows its own data, to end at end.
+	resize func(header []byte, end int64)
+}
+
+// writeTo writes the executable exe, without its signature or zip file,
+// to w, with its headers changed for what follows it to end at end.
+func (img *exeImage) writeTo(w io.Writer, exe io.ReaderAt, end int64) error {
+	if img.resize != nil {
+		img.resize(img.header, end)
+	}
+	if _, err := w.Write(img.header); err != nil {
+		return err
+	}
+	hdr_size := int64(len(img.header))
+	_, err := io.Copy(w, io.NewSectionReader(exe, hdr_size, img.end-hdr_size))
+	return err
+}
+
+// zipStartIn returns the offset of the start of the zip file which
+// ends at end in exe, if there is one starting at from or later: the
+// offset of its first local file header, or of its end of central
+// directory record if it has no files.
+func zipStartIn(exe io.ReaderAt, from, end int64) (int64, bool) {
+	zr, err := zip.NewReader(io.NewSectionReader(exe, 0, end), end)
+	if err != nil {
+		return 0, false
+	}
+	start := int64(-1)
+	for _, f := range zr.File {
+		data, err := f.DataOffset()
+		if err != nil {
+			return 0, false
+		}
+		// Zip writers put the same extra fields in the local and
+		// central headers, so the local header can be found from the
+		// central one.
+		hdr := data - 30 - int64(len(f.Name)) - int64(len(f.Extra))
+		var sig [4]byte
+		if _, err := exe.ReadAt(sig[:], hdr); err != nil || string(sig[:]) != "PK\x03\x04" {
+			return 0, false
+		}
+		if start < 0 || hdr < start {
+			start = hdr
+		}
+	}
+	if start < 0 {
+		// The record is at most 22 bytes and the comment from the
+		// end, which may be padded before a signature.
+		n := 22 + int64(len(zr.Comment)) + 64
+		if n > end {
+			n = end
+		}
+		buf := make([]byte, n)
+		if _, err := exe.ReadAt(buf, end-n); err != nil {
+			return 0, false
+		}
+		i := bytes.LastIndex(buf, []byte("PK\x05\x06"))
+		if i < 0 {
+			return 0, false
+		}
+		start = end - n + int64(i)
+	}
+	return start, start >= from
+}
+
+// exeImageOf returns how to write the executable exe without its
+// signature, or the zip file embedded in it.
+func exeImageOf(exe io.ReaderAt, size int64) (*exeImage, error) {
+	if file, err := macho.NewFile(exe); err == nil {
+		return machoImage(exe, size, file)
+	}
+	if file, err := pe.NewFile(exe); err == nil {
+		return peImage(exe, size, file)
+	}
+	if _, err := elf.NewFile(exe); err == nil {
+		from, err := exeDataEndElf(exe)
+		if err != nil {
+			return nil, err
+		}
+		img := &exeImage{end: size}
+		if start, ok := zipStartIn(exe, from, size); ok {
+			img.end = start
+		}
+		return img, nil
+	}
+	return nil, errors.New("Couldn't Open As Executable")
+}
+
+// machoImage removes the code signature load command from the headers
+// of a Mach-O executable, and resizes its __LINKEDIT segment, which
+// must be last in the file to be signed, to cover the zip file.
+func machoImage(exe io.ReaderAt, size int64, file *macho.File) (*exeImage, error) {
+	hdr_size := 28
+	if file.Magic == macho.Magic64 {
+		hdr_size = 32
+	}
+	header := make([]byte, hdr_size+int(file.Cmdsz))
+	if _, err := exe.ReadAt(header, 0); err != nil {
+		return nil, err
+	}
+	bo := file.ByteOrder
+
+	sig_start := size
+	ncmds, cmds := file.Ncmd, append([]byte(nil), header[:hdr_size]...)
+	linkedit, from := -1, int64(0)
+	var seg *macho.Segment
+	for _, load := range file.Loads {
+		raw := load.Raw()
+		if len(raw) >= 16 && bo.Uint32(raw) == lcCodeSignature {
+			sig_start = int64(bo.Uint32(raw[8:]))
+			ncmds--
+			continue
+		}
+		if s, ok := load.(*macho.Segment); ok {
+			if s.Name == "__LINKEDIT" {
+				linkedit, seg = len(cmds), s
+				if int64(s.Offset) > from {
+					from = int64(s.Offset)
+				}
+			} else if end := int64(s.Offset + s.Filesz); end > from {
+				from = end
+			}
+		}
+		cmds = append(cmds, raw...)
+	}
+	if sig_start < from || sig_start > size {
+		return nil, errors.New("resources: code signature outside of the executable's data")
+	}
+	bo.PutUint32(cmds[16:], ncmds)
+	bo.PutUint32(cmds[20:], uint32(len(cmds)-hdr_size))
+	copy(header, cmds)
+	clear(header[len(cmds):])
+
+	img := &exeImage{header: header, end: sig_start}
+	if start, ok := zipStartIn(exe, from, sig_start); ok {
+		img.end = start
+	}
+	if seg != nil {
+		img.resize = func(header []byte, end int64) {
+			filesz := uint64(end) - seg.Offset
+			vmsize := (filesz + machoPageSize - 1) &^ (machoPageSize - 1)
+			if vmsize < seg.Memsz {
+				vmsize = seg.Memsz
+			}
+			if file.Magic == macho.Magic64 {
+				bo.PutUint64(header[linkedit+32:], vmsize)
+				bo.PutUint64(header[linkedit+48:], filesz)
+			} else {
+				bo.PutUint32(header[linkedit+28:], uint32(vmsize))
+				bo.PutUint32(header[linkedit+36:], uint32(filesz))
+			}
+		}
+	}
+	return img, nil
+}
+
+// peImage removes the certificate table (an Authenticode signature)
+// from the data directories of a PE executable, and its checksum, which
+// signing sets.
+func peImage(exe io.ReaderAt, size int64, file *pe.File) (*exeImage, error) {
+	from, err := exeDataEndPe(exe)
+	if err != nil {
+		return nil, err
+	}
+	var dirs []pe.DataDirectory
+	dirs_at := int64(96)
+	switch hdr := file.OptionalHeader.(type) {
+	case *pe.OptionalHeader32:
+		dirs = hdr.DataDirectory[:hdr.NumberOfRvaAndSizes]
+	case *pe.OptionalHeader64:
+		dirs = hdr.DataDirectory[:hdr.NumberOfRvaAndSizes]
+		dirs_at = 112
+	}
+
+	img := &exeImage{end: size}
+	if len(dirs) > pe.IMAGE_DIRECTORY_ENTRY_SECURITY && dirs[pe.IMAGE_DIRECTORY_ENTRY_SECURITY].Size != 0 {
+		var lfanew [4]byte
+		if _, err := exe.ReadAt(lfanew[:], 0x3c); err != nil {
+			return nil, err
+		}
+		opt := int64(binary.LittleEndian.Uint32(lfanew[:])) + 4 + 20
+		entry := opt + dirs_at + 8*pe.IMAGE_DIRECTORY_ENTRY_SECURITY
+		img.header = make([]byte, entry+8)
+		if _, err := exe.ReadAt(img.header, 0); err != nil {
+			return nil, err
+		}
+		clear(img.header[opt+64 : opt+68]) // CheckSum
+		clear(img.header[entry:])
+		img.end = int64(dirs[pe.IMAGE_DIRECTORY_ENTRY_SECURITY].VirtualAddress)
+		if img.end < from || img.end > size {
+			return nil, errors.New("resources: certificate table outside of the executable's data")
+		}
+	}
+	if start, ok := zipStartIn(exe, from, img.end); ok {
+		img.end = start
+	}
+	return img, nil
+}
+
+// StripZip writes the executable exe to w, without any zip file
+// embedded in it, as by EmbedZip, or its code signature.
+//
+// Only zip files after the executable's own data are found, which is
+// where EmbedZip and the "cat assets.zip >> myApp" method both put
+// them.
+func StripZip(w io.Writer, exe io.ReaderAt, size int64) error {
+	img, err := exeImageOf(exe, size)
+	if err != nil {
+		return err
+	}
+	return img.writeTo(w, exe, img.end)
+}
+
+// EmbedZip writes the executable exe to w, followed by a zip file
+// containing the resources listed by src. Any zip file already
+// embedded in exe is replaced.
+//
+// The offsets in the zip file are relative to the start of the
+// executable, so the result can be opened directly by OpenZip
+// without any adjustments (eg: by "zip -A").
+//
+// A code signature would be invalidated by the zip file, so it is
+// removed: sign the result afterwards, eg: with "codesign -s" on macOS,
+// where darwin/arm64 executables must be signed to run, or signtool on
+// Windows. In Mach-O executables, the zip file is added to the end of
+// the __LINKEDIT segment, where codesign requires it. OpenZip finds the
+// zip file before the new signature.
+func EmbedZip(w io.Writer, exe io.ReaderAt, size int64, src Lister) error {
+	img, err := exeImageOf(exe, size)
+	if err != nil {
+		return err
+	}
+
+	// The headers of the executable may depend on the size of the zip
+	// file, so it is written first.
+	var zbuf bytes.Buffer
+	var zw *zip.Writer
+	if img.resize != nil {
+		zw = zip.NewWriter(&zbuf)
+	} else {
+		if err := img.writeTo(w, exe, img.end); err != nil {
+			return err
+		}
+		zw = zip.NewWriter(w)
+	}
+	zw.SetOffset(img.end)
+	if err := WriteZip(zw, src); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	if img.resize == nil {
+		return nil
+	}
+	if err := img.writeTo(w, exe, img.end+int64(zbuf.Len())); err != nil {
+		return err
+	}
+	_, err = zbuf.WriteTo(w)
+	return err
+}
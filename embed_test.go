@@ -0,0 +1,195 @@
+package resources
+
+import (
+	"bytes"
+	"debug/macho"
+	"debug/pe"
+	"encoding/binary"
+	"io/ioutil"
+	. "testing"
+)
+
+func TestEmbedZip(t *T) {
+	exe_path, err := ExecutablePath()
+	if err != nil {
+		t.Skip("ExecutablePath():", err)
+	}
+	exe, err := ioutil.ReadFile(exe_path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := exeImageOf(bytes.NewReader(exe), int64(len(exe))); err != nil {
+		t.Skip("test binary not a known executable format:", err)
+	}
+
+	zip := CreateTestZip(t)
+	src, err := OpenZipReader(zip, int64(zip.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Embed twice, to check the first zip file is replaced.
+	var once, twice bytes.Buffer
+	if err := EmbedZip(&once, bytes.NewReader(exe), int64(len(exe)), src.(Lister)); err != nil {
+		t.Fatal("EmbedZip():", err)
+	}
+	if err := EmbedZip(&twice, bytes.NewReader(once.Bytes()), int64(once.Len()), src.(Lister)); err != nil {
+		t.Fatal("EmbedZip() over embedded zip:", err)
+	}
+	if !bytes.Equal(once.Bytes(), twice.Bytes()) {
+		t.Errorf("re-embedding changed the executable, %d bytes -> %d bytes", once.Len(), twice.Len())
+	}
+
+	zb, err := OpenZipReader(bytes.NewReader(once.Bytes()), int64(once.Len()))
+	if err != nil {
+		t.Fatal("OpenZipReader(embedded):", err)
+	}
+	for _, file := range files {
+		if got := readAll(t, zb, file.Path); got != string(file.Contents) {
+			t.Errorf("%s - Contents Differ", file.Path)
+		}
+	}
+
+	var stripped bytes.Buffer
+	if err := StripZip(&stripped, bytes.NewReader(once.Bytes()), int64(once.Len())); err != nil {
+		t.Fatal("StripZip():", err)
+	}
+	if !bytes.Equal(stripped.Bytes(), exe) {
+		t.Errorf("StripZip() = %d bytes, want original %d bytes", stripped.Len(), len(exe))
+	}
+}
+
+// embedSigned embeds the test zip file in exe, which sign signs, and
+// checks the signature was removed, that the zip file can be read
+// before a new signature, and that it is replaced by embedding it again
+// in the signed executable.
+func embedSigned(t *T, exe []byte, sign func(exe []byte) []byte) []byte {
+	zip := CreateTestZip(t)
+	src, err := OpenZipReader(zip, int64(zip.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	if err := EmbedZip(&out, bytes.NewReader(exe), int64(len(exe)), src.(Lister)); err != nil {
+		t.Fatal("EmbedZip(signed):", err)
+	}
+
+	signed := sign(bytes.Clone(out.Bytes()))
+	for _, exe := range [][]byte{out.Bytes(), signed} {
+		zb, err := OpenZipReader(bytes.NewReader(exe), int64(len(exe)))
+		if err != nil {
+			t.Fatal("OpenZipReader(embedded):", err)
+		}
+		for _, file := range files {
+			if got := readAll(t, zb, file.Path); got != string(file.Contents) {
+				t.Errorf("%s - Contents Differ", file.Path)
+			}
+		}
+	}
+
+	var again bytes.Buffer
+	if err := EmbedZip(&again, bytes.NewReader(signed), int64(len(signed)), src.(Lister)); err != nil {
+		t.Fatal("EmbedZip() over signed embedded zip:", err)
+	}
+	if !bytes.Equal(again.Bytes(), out.Bytes()) {
+		t.Errorf("re-embedding in the signed executable = %d bytes, want %d bytes", again.Len(), out.Len())
+	}
+	return out.Bytes()
+}
+
+func TestEmbedZipMachoSigned(t *T) {
+	// A 64-bit little-endian Mach-O executable, with a __LINKEDIT
+	// segment at 0x1000 ending with a code signature.
+	const linkedit, data_size, sig_size = 0x1000, 0x100, 0x40
+	le := binary.LittleEndian
+	exe := make([]byte, linkedit+data_size)
+	le.PutUint32(exe[0:], macho.Magic64)
+	le.PutUint32(exe[4:], uint32(macho.CpuArm64))
+	le.PutUint32(exe[12:], uint32(macho.TypeExec))
+	le.PutUint32(exe[16:], 2)     // ncmds
+	le.PutUint32(exe[20:], 72+16) // sizeofcmds
+	seg := exe[32:]
+	le.PutUint32(seg[0:], uint32(macho.LoadCmdSegment64))
+	le.PutUint32(seg[4:], 72)
+	copy(seg[8:], "__LINKEDIT")
+	le.PutUint64(seg[32:], 0x100000) // vmsize
+	le.PutUint64(seg[40:], linkedit)
+	for i := range data_size {
+		exe[linkedit+i] = byte(i)
+	}
+	sign := func(exe []byte) []byte {
+		cmd := exe[32+72:]
+		le.PutUint32(cmd[0:], lcCodeSignature)
+		le.PutUint32(cmd[4:], 16)
+		le.PutUint32(cmd[8:], uint32(len(exe)))
+		le.PutUint32(cmd[12:], sig_size)
+		le.PutUint32(exe[16:], 2)
+		le.PutUint32(exe[20:], 72+16)
+		le.PutUint64(seg[48:], uint64(len(exe)+sig_size-linkedit))
+		return append(exe, bytes.Repeat([]byte{0xfa}, sig_size)...)
+	}
+	exe = sign(exe)
+
+	out := embedSigned(t, exe, func(exe []byte) []byte {
+		seg = exe[32:]
+		return sign(exe)
+	})
+	file, err := macho.NewFile(bytes.NewReader(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(file.Loads) != 1 {
+		t.Errorf("embedded executable has %d load commands, want only __LINKEDIT", len(file.Loads))
+	}
+	if s := file.Segment("__LINKEDIT"); s == nil || s.Offset+s.Filesz != uint64(len(out)) {
+		t.Errorf("__LINKEDIT = %+v, want it to end at %d", s, len(out))
+	}
+	if !bytes.Equal(out[linkedit:linkedit+data_size], exe[linkedit:linkedit+data_size]) {
+		t.Error("__LINKEDIT data changed")
+	}
+}
+
+func TestEmbedZipPeSigned(t *T) {
+	// A PE32+ executable, with one section at 0x200, followed by a
+	// certificate table.
+	const lfanew, opt, sect, data, data_size, cert_size = 0x40, 0x58, 0x58 + 240, 0x200, 0x100, 0x40
+	le := binary.LittleEndian
+	exe := make([]byte, data+data_size)
+	copy(exe, "MZ")
+	le.PutUint32(exe[0x3c:], lfanew)
+	copy(exe[lfanew:], "PE\x00\x00")
+	le.PutUint16(exe[lfanew+4:], pe.IMAGE_FILE_MACHINE_AMD64)
+	le.PutUint16(exe[lfanew+6:], 1)    // NumberOfSections
+	le.PutUint16(exe[lfanew+20:], 240) // SizeOfOptionalHeader
+	le.PutUint16(exe[opt:], 0x20b)     // PE32+
+	le.PutUint32(exe[opt+108:], 16)    // NumberOfRvaAndSizes
+	copy(exe[sect:], ".text")
+	le.PutUint32(exe[sect+16:], data_size) // SizeOfRawData
+	le.PutUint32(exe[sect+20:], data)      // PointerToRawData
+	for i := range data_size {
+		exe[data+i] = byte(i)
+	}
+	sign := func(exe []byte) []byte {
+		for len(exe)%8 != 0 {
+			exe = append(exe, 0)
+		}
+		le.PutUint32(exe[opt+64:], 0x1234) // CheckSum
+		le.PutUint32(exe[opt+112+8*pe.IMAGE_DIRECTORY_ENTRY_SECURITY:], uint32(len(exe)))
+		le.PutUint32(exe[opt+112+8*pe.IMAGE_DIRECTORY_ENTRY_SECURITY+4:], cert_size)
+		return append(exe, bytes.Repeat([]byte{0xfa}, cert_size)...)
+	}
+	exe = sign(exe)
+
+	out := embedSigned(t, exe, sign)
+	file, err := pe.NewFile(bytes.NewReader(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+	hdr := file.OptionalHeader.(*pe.OptionalHeader64)
+	if hdr.CheckSum != 0 || hdr.DataDirectory[pe.IMAGE_DIRECTORY_ENTRY_SECURITY].Size != 0 {
+		t.Errorf("embedded executable still has a checksum or certificate table: %+v", hdr)
+	}
+	if !bytes.Equal(out[data:data+data_size], exe[data:data+data_size]) {
+		t.Error("section data changed")
+	}
+}
@@ -0,0 +1,389 @@
+package resources
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// Encrypted resources are stored as a header, made of encMagic and a
+// random nonce prefix, followed by the content split into chunks of
+// encChunkSize bytes, each sealed with AES-GCM. The nonce of a chunk
+// is the prefix, the chunk number, and a flag marking the last chunk,
+// so chunks can't be reordered, dropped, or truncated. The header and
+// the path of the resource are authenticated with every chunk, so
+// resources can't be swapped either.
+const (
+	encMagic       = "RGE1"
+	encPrefixSize  = 7
+	encHeaderSize  = len(encMagic) + encPrefixSize
+	encChunkSize   = 64 * 1024
+	encOverhead    = 16 // AES-GCM tag size
+	encRecordSize  = encChunkSize + encOverhead
+	encNonceLength = 12
+)
+
+var (
+	// ErrNotEncrypted is returned when opening a resource which
+	// doesn't have the header of an encrypted resource.
+	ErrNotEncrypted = errors.New("resources: resource not encrypted")
+
+	// ErrDecrypt is returned when reading an encrypted resource which
+	// was modified, or encrypted with a different key.
+	ErrDecrypt = errors.New("resources: decryption failed")
+)
+
+// A KeyProvider supplies the key of encrypted resources. The key must
+// be 16, 24, or 32 bytes long, to select AES-128, AES-192, or AES-256.
+type KeyProvider interface {
+	Key() ([]byte, error)
+}
+
+// KeyFunc is a KeyProvider calling a function to get the key.
+type KeyFunc func() ([]byte, error)
+
+func (kf KeyFunc) Key() ([]byte, error) {
+	return kf()
+}
+
+// StaticKey returns a KeyProvider which always returns key.
+func StaticKey(key []byte) KeyProvider {
+	return KeyFunc(func() ([]byte, error) { return key, nil })
+}
+
+// KeyFromEnv returns a KeyProvider which reads the base64 encoded key
+// from the named environment variable.
+func KeyFromEnv(name string) KeyProvider {
+	return KeyFunc(func() ([]byte, error) {
+		value := os.Getenv(name)
+		if value == "" {
+			return nil, errors.New("resources: key variable " + name + " not set")
+		}
+		return base64.StdEncoding.DecodeString(value)
+	})
+}
+
+// KeyFilePrefix starts key files holding a base64 encoded key, rather
+// than the raw key.
+const KeyFilePrefix = "base64:"
+
+// KeyFromFile returns a KeyProvider which reads the key from the named
+// file. The file holds either the raw key, which must be 16, 24 or 32
+// bytes long, or KeyFilePrefix followed by the base64 encoded key, eg:
+//
+//	base64:q7vJ3mJ8M5h0Jm7ZfQm4YH0Q6QbG9qXc3lEw8Hh0b2Q=
+//
+// Space around the encoded key is ignored.
+func KeyFromFile(path string) KeyProvider {
+	return KeyFunc(func() ([]byte, error) {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if encoded, ok := strings.CutPrefix(string(data), KeyFilePrefix); ok {
+			return base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+		}
+		switch len(data) {
+		case 16, 24, 32:
+			return data, nil
+		}
+		return nil, fmt.Errorf("resources: key file %s: raw key is %d bytes, not 16, 24 or 32 (base64 keys need the prefix %q)", path, len(data), KeyFilePrefix)
+	})
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// encState is the state shared by encrypting and decrypting streams.
+type encState struct {
+	aead    cipher.AEAD
+	header  [encHeaderSize]byte
+	ad      []byte
+	counter uint32
+}
+
+func newEncState(key, header []byte, path string) (*encState, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	es := &encState{aead: aead}
+	copy(es.header[:], header)
+	es.ad = append(es.header[:], path...)
+	return es, nil
+}
+
+// nonce returns the nonce of the next chunk.
+func (es *encState) nonce(last bool) []byte {
+	nonce := make([]byte, encNonceLength)
+	copy(nonce, es.header[len(encMagic):])
+	binary.BigEndian.PutUint32(nonce[encPrefixSize:], es.counter)
+	if last {
+		nonce[encNonceLength-1] = 1
+	}
+	es.counter++
+	return nonce
+}
+
+type encryptWriter struct {
+	*encState
+	w   io.Writer
+	buf []byte
+}
+
+// NewEncryptWriter returns a writer which encrypts the content of the
+// resource at path, in the form read by OpenEncrypted, writing it to
+// w. Close must be called to write the final chunk, it does not close
+// w.
+func NewEncryptWriter(w io.Writer, key []byte, path string) (io.WriteCloser, error) {
+	header := make([]byte, encHeaderSize)
+	copy(header, encMagic)
+	if _, err := io.ReadFull(rand.Reader, header[len(encMagic):]); err != nil {
+		return nil, err
+	}
+	es, err := newEncState(key, header, path)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(header); err != nil {
+		return nil, err
+	}
+	return &encryptWriter{encState: es, w: w, buf: make([]byte, 0, encRecordSize)}, nil
+}
+
+func (ew *encryptWriter) seal(last bool) error {
+	record := ew.aead.Seal(ew.buf[:0], ew.nonce(last), ew.buf, ew.ad)
+	ew.buf = record[:0]
+	_, err := ew.w.Write(record)
+	return err
+}
+
+func (ew *encryptWriter) Write(p []byte) (n int, err error) {
+	for len(p) > 0 {
+		// Only seal a full chunk once more data arrives, so the last
+		// chunk is always sealed by Close.
+		if len(ew.buf) == encChunkSize {
+			if err := ew.seal(false); err != nil {
+				return n, err
+			}
+		}
+		m := copy(ew.buf[len(ew.buf):encChunkSize], p)
+		ew.buf = ew.buf[:len(ew.buf)+m]
+		n, p = n+m, p[m:]
+	}
+	return n, nil
+}
+
+func (ew *encryptWriter) Close() error {
+	return ew.seal(true)
+}
+
+type decryptReader struct {
+	*encState
+	r     *bufio.Reader
+	rc    io.Closer
+	plain []byte
+	buf   []byte
+	err   error
+}
+
+// NewDecryptReader returns a reader which decrypts the content of the
+// resource at path, as written by NewEncryptWriter, from r.
+func NewDecryptReader(r io.Reader, key []byte, path string) (io.Reader, error) {
+	return newDecryptReader(ioutil.NopCloser(r), key, path)
+}
+
+func newDecryptReader(rc io.ReadCloser, key []byte, path string) (*decryptReader, error) {
+	header := make([]byte, encHeaderSize)
+	if _, err := io.ReadFull(rc, header); err != nil || string(header[:len(encMagic)]) != encMagic {
+		return nil, ErrNotEncrypted
+	}
+	es, err := newEncState(key, header, path)
+	if err != nil {
+		return nil, err
+	}
+	return &decryptReader{
+		encState: es,
+		r:        bufio.NewReaderSize(rc, encRecordSize),
+		rc:       rc,
+		buf:      make([]byte, encRecordSize),
+	}, nil
+}
+
+// open decrypts the next chunk into dr.plain.
+func (dr *decryptReader) open() error {
+	n, err := io.ReadFull(dr.r, dr.buf)
+	last := err == io.ErrUnexpectedEOF || err == io.EOF
+	if err == nil {
+		_, perr := dr.r.Peek(1)
+		last = perr == io.EOF
+	} else if !last {
+		return err
+	}
+
+	dr.plain, err = dr.aead.Open(dr.buf[:0], dr.nonce(last), dr.buf[:n], dr.ad)
+	if err != nil {
+		return ErrDecrypt
+	}
+	if last {
+		return io.EOF
+	}
+	return nil
+}
+
+func (dr *decryptReader) Read(p []byte) (int, error) {
+	for len(dr.plain) == 0 && dr.err == nil {
+		dr.err = dr.open()
+	}
+	n := copy(p, dr.plain)
+	dr.plain = dr.plain[n:]
+	if len(dr.plain) == 0 && dr.err != nil {
+		return n, dr.err
+	}
+	return n, nil
+}
+
+func (dr *decryptReader) Close() error {
+	return dr.rc.Close()
+}
+
+// decryptedSize returns the size of the content of an encrypted
+// resource of the given size.
+func decryptedSize(size int64) int64 {
+	size -= int64(encHeaderSize)
+	records := (size + encRecordSize - 1) / encRecordSize
+	return size - records*encOverhead
+}
+
+type decryptedInfo struct {
+	os.FileInfo
+}
+
+func (di decryptedInfo) Size() int64 {
+	return decryptedSize(di.FileInfo.Size())
+}
+
+type encryptedResource struct {
+	Resource
+	eb *encryptedBundle
+}
+
+func (er *encryptedResource) Open() (io.ReadCloser, error) {
+	rdr, err := er.Resource.Open()
+	if err != nil {
+		return nil, err
+	}
+	return er.eb.decrypt(rdr, er.Path())
+}
+
+// Stat reports the size of the decrypted content.
+func (er *encryptedResource) Stat() (os.FileInfo, error) {
+	info, err := er.Resource.Stat()
+	if err != nil || info.IsDir() {
+		return info, err
+	}
+	return decryptedInfo{info}, nil
+}
+
+func (er *encryptedResource) String() string {
+	return er.Path()
+}
+
+type encryptedBundle struct {
+	bundle Bundle
+	keys   KeyProvider
+}
+
+// OpenEncrypted wraps a bundle of resources encrypted by
+// NewEncryptWriter (eg: packed with PackOptions.Key set), decrypting
+// them as they are read, with the key from keys.
+//
+// Reading a resource which was modified returns ErrDecrypt, and
+// opening one which isn't encrypted returns ErrNotEncrypted.
+//
+// The returned Bundle implements Searcher and Lister, which find
+// nothing if the wrapped bundle doesn't implement them. Closing it
+// closes the wrapped bundle.
+func OpenEncrypted(b Bundle, keys KeyProvider) Bundle {
+	return &encryptedBundle{bundle: b, keys: keys}
+}
+
+func (eb *encryptedBundle) decrypt(rdr io.ReadCloser, path string) (io.ReadCloser, error) {
+	key, err := eb.keys.Key()
+	if err == nil {
+		var dr *decryptReader
+		if dr, err = newDecryptReader(rdr, key, path); err == nil {
+			return dr, nil
+		}
+	}
+	rdr.Close()
+	return nil, err
+}
+
+func (eb *encryptedBundle) wrapped() Bundle {
+	return eb.bundle
+}
+
+func (eb *encryptedBundle) Close() error {
+	return eb.bundle.Close()
+}
+
+func (eb *encryptedBundle) Open(path string) (io.ReadCloser, error) {
+	rdr, err := eb.bundle.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return eb.decrypt(rdr, path)
+}
+
+func (eb *encryptedBundle) wrap(rsrcs []Resource) []Resource {
+	wrapped := make([]Resource, len(rsrcs))
+	for i, rsrc := range rsrcs {
+		wrapped[i] = &encryptedResource{rsrc, eb}
+	}
+	return wrapped
+}
+
+func (eb *encryptedBundle) Find(path string) (Resource, error) {
+	searcher, ok := eb.bundle.(Searcher)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	rsrc, err := searcher.Find(path)
+	if err != nil {
+		return nil, err
+	}
+	return &encryptedResource{rsrc, eb}, nil
+}
+
+func (eb *encryptedBundle) Glob(pattern string) ([]Resource, error) {
+	searcher, ok := eb.bundle.(Searcher)
+	if !ok {
+		return nil, nil
+	}
+	rsrcs, err := searcher.Glob(pattern)
+	return eb.wrap(rsrcs), err
+}
+
+func (eb *encryptedBundle) List() ([]Resource, error) {
+	lister, ok := eb.bundle.(Lister)
+	if !ok {
+		return nil, nil
+	}
+	rsrcs, err := lister.List()
+	return eb.wrap(rsrcs), err
+}
@@ -0,0 +1,140 @@
+package resources
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/base64"
+	"io/ioutil"
+	"math/rand"
+	"path/filepath"
+	. "testing"
+)
+
+func TestEncryptRoundTrip(t *T) {
+	key := make([]byte, 32)
+	for _, size := range []int{0, 1, encChunkSize - 1, encChunkSize, encChunkSize + 1, 3*encChunkSize + 17} {
+		plain := make([]byte, size)
+		rand.Read(plain)
+
+		buf := new(bytes.Buffer)
+		ew, err := NewEncryptWriter(buf, key, "file")
+		if err != nil {
+			t.Fatal(err)
+		}
+		ew.Write(plain)
+		if err := ew.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if got := decryptedSize(int64(buf.Len())); got != int64(size) {
+			t.Errorf("size %d: decryptedSize() = %d", size, got)
+		}
+		cipher := buf.Bytes()
+
+		dr, err := NewDecryptReader(bytes.NewReader(cipher), key, "file")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, err := ioutil.ReadAll(dr); err != nil || !bytes.Equal(got, plain) {
+			t.Errorf("size %d: decrypted %d bytes, err = %v", size, len(got), err)
+		}
+
+		// Truncation, modification, and moving to another path
+		// must all be detected.
+		truncated := cipher[:len(cipher)-1]
+		if size > encChunkSize {
+			truncated = cipher[:encHeaderSize+encRecordSize]
+		}
+		modified := append([]byte(nil), cipher...)
+		modified[len(modified)-1] ^= 1
+		for name, data := range map[string][]byte{"truncated": truncated, "modified": modified, "moved": cipher} {
+			path := "file"
+			if name == "moved" {
+				path = "other"
+			}
+			dr, err := NewDecryptReader(bytes.NewReader(data), key, path)
+			if err == nil {
+				_, err = ioutil.ReadAll(dr)
+			}
+			if err != ErrDecrypt {
+				t.Errorf("size %d: reading %s: %v, want ErrDecrypt", size, name, err)
+			}
+		}
+	}
+}
+
+func TestEncryptedBundle(t *T) {
+	key := []byte("0123456789abcdef")
+	zip_rdr := CreateTestZip(t)
+	src, err := OpenZipReader(zip_rdr, int64(zip_rdr.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := new(bytes.Buffer)
+	zw := zip.NewWriter(buf)
+	if err := Pack(zw, src.(Lister), &PackOptions{Key: key}); err != nil {
+		t.Fatal("Pack():", err)
+	}
+	zw.Close()
+
+	packed, err := OpenZipData(buf.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(buf.Bytes(), files[0].Contents) {
+		t.Error("packed zip contains plain text")
+	}
+
+	eb := OpenEncrypted(packed, StaticKey(key))
+	for _, file := range files {
+		if got := readAll(t, eb, file.Path); got != string(file.Contents) {
+			t.Errorf("%s - Contents Differ", file.Path)
+		}
+		rsrc, err := eb.(Searcher).Find(file.Path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info, _ := rsrc.Stat(); info.Size() != int64(len(file.Contents)) {
+			t.Errorf("%s: Stat().Size() = %d, want %d", file.Path, info.Size(), len(file.Contents))
+		}
+	}
+
+	wrong := OpenEncrypted(packed, StaticKey([]byte("fedcba9876543210")))
+	rdr, err := wrong.Open(files[0].Path)
+	if err == nil {
+		_, err = ioutil.ReadAll(rdr)
+	}
+	if err != ErrDecrypt {
+		t.Errorf("reading with the wrong key: %v, want ErrDecrypt", err)
+	}
+}
+
+func TestKeyFromFile(t *T) {
+	dir := t.TempDir()
+	key := []byte("0123456789abcdef0123456789abcdef")
+	// A raw key which is also valid base64 mustn't be decoded.
+	b64 := []byte("MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3")
+	for _, test := range []struct {
+		data []byte
+		want []byte
+	}{
+		{key, key},
+		{b64, b64},
+		{[]byte(KeyFilePrefix + base64.StdEncoding.EncodeToString(key) + "\n"), key},
+		{[]byte(base64.StdEncoding.EncodeToString(key) + "\n"), nil},
+	} {
+		file := filepath.Join(dir, "key")
+		if err := ioutil.WriteFile(file, test.data, 0600); err != nil {
+			t.Fatal(err)
+		}
+		got, err := KeyFromFile(file).Key()
+		if test.want == nil {
+			if err == nil {
+				t.Errorf("Key() of %q succeeded", test.data)
+			}
+			continue
+		}
+		if err != nil || !bytes.Equal(got, test.want) {
+			t.Errorf("Key() of %q = %q, %v, want %q", test.data, got, err, test.want)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package resources
 
 import (
 	"errors"
+	"strings"
 )
 
 var (
@@ -9,3 +10,35 @@ var (
 	ErrEscapeRoot  error = errors.New("resources: path escapes root")
 	ErrNotRelative error = errors.New("resources: path not relative")
 )
+
+// A MultiError collects several errors that occurred during one
+// operation, such as closing every sub-bundle of an OwningSequence.
+//
+// MultiErrors support errors.Is and errors.As, which test each of
+// the contained errors in turn.
+type MultiError []error
+
+func (me MultiError) Error() string {
+	msgs := make([]string, len(me))
+	for i, err := range me {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Unwrap returns the contained errors.
+func (me MultiError) Unwrap() []error {
+	return me
+}
+
+// errorList returns nil if errs is empty, the only error if
+// there is one, or a MultiError containing all of them.
+func errorList(errs []error) error {
+	switch len(errs) {
+	case 0:
+		return nil
+	case 1:
+		return errs[0]
+	}
+	return MultiError(errs)
+}
@@ -24,7 +24,13 @@ func (f *fsResource) Stat() (os.FileInfo, error) {
 }
 
 func (f *fsResource) Open() (io.ReadCloser, error) {
-	return os.Open(f.real_path())
+	file, err := os.Open(f.real_path())
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	return file, nil
 }
 
 func (f *fsResource) String() string {
@@ -102,3 +108,30 @@ func (fb *fsBundle) Glob(pattern string) ([]Resource, error) {
 	}
 	return rsrcs, nil
 }
+
+type dirBundle struct {
+	*fsBundle
+}
+
+// OpenDir is like OpenFS, but the returned Bundle also implements
+// the Lister interface, listing every file beneath base_dir.
+func OpenDir(base_dir string) Bundle {
+	return &dirBundle{OpenFS(base_dir).(*fsBundle)}
+}
+
+func (db *dirBundle) List() ([]Resource, error) {
+	var list []Resource
+	err := filepath.Walk(db.base, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			rel, err := filepath.Rel(db.base, path)
+			if err == nil {
+				list = append(list, db.file(filepath.ToSlash(rel)))
+			}
+		}
+		return nil
+	})
+	return list, err
+}
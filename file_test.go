@@ -19,3 +19,10 @@ func TestFS(t *T) {
 	tryFSGlob(t, b, "*")
 	tryFSGlob(t, b, "*/*")
 }
+
+func TestFSOpenMissing(t *T) {
+	b := OpenFS(t.TempDir())
+	if _, err := b.Open("missing.txt"); err != ErrNotFound {
+		t.Errorf("Open(missing.txt) = %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,150 @@
+package resources
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// FingerprintLen is the number of hex digits of the content hash in
+// fingerprinted paths.
+const FingerprintLen = 8
+
+// Fingerprints map the paths of resources to fingerprinted paths,
+// which contain a hash of their contents, eg: "app.js" to
+// "app.3f9a1c2e.js". As the fingerprinted path changes whenever the
+// contents do, they can be served with headers allowing them to be
+// cached forever.
+//
+// Fingerprints are marshalled to JSON as an object of paths to
+// fingerprinted paths, for use as a manifest.
+type Fingerprints struct {
+	paths   map[string]string // path -> fingerprinted path
+	reverse map[string]string // fingerprinted path -> path
+}
+
+// fingerprintPath adds the fingerprint to a path, before its
+// extension.
+func fingerprintPath(p, fingerprint string) string {
+	stem, ext := splitExt(p)
+	return stem + "." + fingerprint + ext
+}
+
+// NewFingerprints hashes the contents of every resource listed by src.
+func NewFingerprints(src Lister) (*Fingerprints, error) {
+	list, err := src.List()
+	if err != nil {
+		return nil, err
+	}
+	paths := make(map[string]string, len(list))
+	for _, rsrc := range list {
+		if info, err := rsrc.Stat(); err != nil {
+			return nil, err
+		} else if info.IsDir() {
+			continue
+		}
+		rdr, err := rsrc.Open()
+		if err != nil {
+			return nil, err
+		}
+		if paths[rsrc.Path()], err = fingerprintOf(rsrc.Path(), rdr); err != nil {
+			return nil, err
+		}
+	}
+	return newFingerprints(paths), nil
+}
+
+// fingerprintOf returns the fingerprinted path of the resource at p,
+// hashing the contents read from rdr, which it closes.
+func fingerprintOf(p string, rdr io.ReadCloser) (string, error) {
+	defer rdr.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, rdr); err != nil {
+		return "", err
+	}
+	sum := hex.EncodeToString(hash.Sum(nil))
+	return fingerprintPath(p, sum[:FingerprintLen]), nil
+}
+
+// FingerprintPath returns the fingerprinted path of the resource at p
+// in b, from a hash of its current contents, eg: to check that a
+// fingerprinted path is still up to date.
+func FingerprintPath(b Bundle, p string) (string, error) {
+	rdr, err := b.Open(p)
+	if err != nil {
+		return "", err
+	}
+	return fingerprintOf(p, rdr)
+}
+
+func newFingerprints(paths map[string]string) *Fingerprints {
+	f := &Fingerprints{paths: paths, reverse: make(map[string]string, len(paths))}
+	for p, fp := range paths {
+		f.reverse[fp] = p
+	}
+	return f
+}
+
+// LoadFingerprints reads fingerprints stored as JSON at path in a
+// bundle.
+func LoadFingerprints(b Bundle, path string) (*Fingerprints, error) {
+	data, err := readResource(b, path)
+	if err != nil {
+		return nil, err
+	}
+	f := new(Fingerprints)
+	if err := json.Unmarshal(data, f); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Path returns the fingerprinted path of the resource at p.
+func (f *Fingerprints) Path(p string) (string, bool) {
+	fp, ok := f.paths[p]
+	return fp, ok
+}
+
+// Logical returns the path of the resource with the fingerprinted path
+// fp.
+func (f *Fingerprints) Logical(fp string) (string, bool) {
+	p, ok := f.reverse[fp]
+	return p, ok
+}
+
+func (f *Fingerprints) MarshalJSON() ([]byte, error) {
+	return json.Marshal(f.paths)
+}
+
+func (f *Fingerprints) UnmarshalJSON(data []byte) error {
+	var paths map[string]string
+	if err := json.Unmarshal(data, &paths); err != nil {
+		return err
+	}
+	*f = *newFingerprints(paths)
+	return nil
+}
+
+// FuncMap returns template functions using the fingerprints:
+//
+//	asset "app.js"
+//
+// returns the fingerprinted path of "app.js", with prefix added, eg:
+// "/static/app.3f9a1c2e.js" for the prefix "/static/". It fails if
+// there is no such resource.
+//
+// The map can be passed to the Funcs method of an html/template or
+// text/template Template.
+func (f *Fingerprints) FuncMap(prefix string) map[string]any {
+	return map[string]any{
+		"asset": func(p string) (string, error) {
+			fp, ok := f.Path(p)
+			if !ok {
+				return "", fmt.Errorf("resources: no asset %q", p)
+			}
+			return prefix + fp, nil
+		},
+	}
+}
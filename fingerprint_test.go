@@ -0,0 +1,68 @@
+package resources
+
+import (
+	"bytes"
+	"encoding/json"
+	"html/template"
+	"regexp"
+	. "testing"
+)
+
+func TestFingerprints(t *T) {
+	b := zipOfFiles(t, map[string]string{
+		"app.js":       "console.log(1)",
+		"css/site.css": "body{}",
+		"LICENSE":      "free",
+	})
+	f, err := NewFingerprints(b.(Lister))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fp, ok := f.Path("app.js")
+	t.Log("app.js ->", fp)
+	if !ok || !regexp.MustCompile(`^app\.[0-9a-f]{8}\.js$`).MatchString(fp) {
+		t.Errorf("Path(app.js) = %q, %v", fp, ok)
+	}
+	if p, ok := f.Logical(fp); !ok || p != "app.js" {
+		t.Errorf("Logical(%s) = %q, %v", fp, p, ok)
+	}
+	if fp, _ := f.Path("LICENSE"); !regexp.MustCompile(`^LICENSE\.[0-9a-f]{8}$`).MatchString(fp) {
+		t.Errorf("Path(LICENSE) = %q", fp)
+	}
+
+	// The same contents give the same fingerprint.
+	b2 := zipOfFiles(t, map[string]string{"app.js": "console.log(1)"})
+	f2, err := NewFingerprints(b2.(Lister))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fp2, _ := f2.Path("app.js"); fp2 != fp {
+		t.Errorf("fingerprints differ for the same contents: %s, %s", fp, fp2)
+	}
+
+	data, err := json.Marshal(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Log(string(data))
+	loaded, err := LoadFingerprints(zipOfFiles(t, map[string]string{"assets.json": string(data)}), "assets.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p, _ := loaded.Logical(fp); p != "app.js" {
+		t.Errorf("loaded Logical(%s) = %q", fp, p)
+	}
+
+	tmpl := template.Must(template.New("").Funcs(f.FuncMap("/static/")).Parse(`<script src="{{asset "app.js"}}"></script>`))
+	buf := new(bytes.Buffer)
+	if err := tmpl.Execute(buf, nil); err != nil {
+		t.Fatal(err)
+	}
+	if want := `<script src="/static/` + fp + `"></script>`; buf.String() != want {
+		t.Errorf("template = %s, want %s", buf, want)
+	}
+	if err := template.Must(template.New("").Funcs(f.FuncMap("")).Parse(`{{asset "missing.js"}}`)).Execute(buf, nil); err == nil {
+		t.Error("asset of a missing resource succeeded")
+	}
+}
@@ -0,0 +1,5 @@
+module gopkg.in/cookieo9/resources-go.v2
+
+go 1.24
+
+require github.com/kardianos/osext v0.0.0-20190222173326-2bc1f35cddc0
@@ -0,0 +1,112 @@
+package htmltemplate
+
+import (
+	"bytes"
+	"html/template"
+	"os"
+	"path/filepath"
+	"strings"
+	. "testing"
+
+	"gopkg.in/cookieo9/resources-go.v2"
+)
+
+// dirOf returns a directory bundle of the given files.
+func dirOf(t *T, files map[string]string) resources.Bundle {
+	dir := t.TempDir()
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return resources.OpenDir(dir)
+}
+
+func execute(t *T, tmpl *template.Template, name string) string {
+	buf := new(bytes.Buffer)
+	if err := tmpl.ExecuteTemplate(buf, name, "<x>"); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestParseTemplates(t *T) {
+	theme := dirOf(t, map[string]string{
+		"header.html": `<h1>themed {{.}}</h1>`,
+	})
+	defaults := dirOf(t, map[string]string{
+		"page.html":   `{{template "header.html" .}}<p>page</p>`,
+		"header.html": `<h1>{{.}}</h1>`,
+		"notes.txt":   `not a template`,
+	})
+
+	tmpl, err := ParseTemplates("page.html", resources.BundleSequence{theme, defaults}, "*.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Log(tmpl.DefinedTemplates())
+	if tmpl.Name() != "page.html" {
+		t.Errorf("root template = %q, want page.html", tmpl.Name())
+	}
+	if got, want := execute(t, tmpl, "page.html"), "<h1>themed &lt;x&gt;</h1><p>page</p>"; got != want {
+		t.Errorf("page.html = %q, want %q", got, want)
+	}
+	if tmpl.Lookup("notes.txt") != nil {
+		t.Error("notes.txt parsed")
+	}
+
+	if _, err := ParseTemplates("page.html", defaults.(resources.Searcher), "*.tmpl"); err == nil {
+		t.Error("ParseTemplates with no matches succeeded")
+	}
+}
+
+// testWatcher reports the changes it's told to.
+type testWatcher struct {
+	fn func(string)
+}
+
+func (tw *testWatcher) Watch(fn func(string)) func() {
+	tw.fn = fn
+	return func() { tw.fn = nil }
+}
+
+func TestReloader(t *T) {
+	dir := t.TempDir()
+	write := func(data string) {
+		if err := os.WriteFile(filepath.Join(dir, "page.html"), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(`{{upper .}}`)
+
+	base := template.New("").Funcs(template.FuncMap{"upper": strings.ToUpper})
+	w := new(testWatcher)
+	r, err := NewReloader(base, resources.OpenDir(dir).(resources.Searcher), w, "*.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := execute(t, r.Template(), "page.html"); got != "&lt;X&gt;" {
+		t.Errorf("page.html = %q", got)
+	}
+
+	write(`v2 {{upper .}}`)
+	w.fn("page.html")
+	if got := execute(t, r.Template(), "page.html"); got != "v2 &lt;X&gt;" {
+		t.Errorf("page.html after change = %q", got)
+	}
+
+	// A broken template keeps the last good one.
+	write(`{{`)
+	w.fn("page.html")
+	if r.Err() == nil {
+		t.Error("Err() = nil after a broken template")
+	}
+	if got := execute(t, r.Template(), "page.html"); got != "v2 &lt;X&gt;" {
+		t.Errorf("page.html after broken change = %q", got)
+	}
+
+	r.Close()
+	if w.fn != nil {
+		t.Error("Close didn't stop watching")
+	}
+}
@@ -1,6 +1,7 @@
 package http
 
 import (
+	"context"
 	"io"
 	"net/http"
 	"net/url"
@@ -8,6 +9,15 @@ import (
 	"gopkg.in/cookieo9/resources-go.v2"
 )
 
+func init() {
+	for _, scheme := range []string{"http", "https"} {
+		scheme := scheme
+		resources.RegisterScheme(scheme, func(arg string) (resources.Bundle, error) {
+			return NewBundle(scheme + ":" + arg)
+		})
+	}
+}
+
 type HttpBundle struct {
 	BaseURL *url.URL
 }
@@ -21,11 +31,21 @@ func NewBundle(baseurl string) (resources.Bundle, error) {
 }
 
 func (hb *HttpBundle) Open(path string) (io.ReadCloser, error) {
+	return hb.OpenContext(context.Background(), path)
+}
+
+// OpenContext implements resources.ContextOpener, so the request is
+// cancelled with ctx, eg: by a Sequence searching layers in parallel.
+func (hb *HttpBundle) OpenContext(ctx context.Context, path string) (io.ReadCloser, error) {
 	dest, err := hb.BaseURL.Parse(path)
 	if err != nil {
 		return nil, err
 	}
-	response, err := http.Get(dest.String())
+	request, err := http.NewRequestWithContext(ctx, "GET", dest.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	response, err := http.DefaultClient.Do(request)
 	if err != nil {
 		return nil, err
 	}
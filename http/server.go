@@ -0,0 +1,197 @@
+package http
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/cookieo9/resources-go.v2"
+)
+
+// Server is an http.Handler serving the resources of a Bundle, with
+// the request's URL path (without the leading slash) as the path of
+// the resource.
+type Server struct {
+	Bundle resources.Bundle
+
+	// Precompressed enables serving compressed resources directly.
+	// If the client accepts one of the registered encodings (see
+	// resources.Encodings), and a resource exists with that
+	// encoding's extension added to the requested path (eg:
+	// "app.js.gz" for "app.js"), its compressed bytes are sent with
+	// the matching Content-Encoding.
+	Precompressed bool
+
+	// Fingerprints, if set, allows resources to be requested by
+	// their fingerprinted paths, which are served with headers
+	// letting them be cached forever. Each request for one hashes
+	// the resource, and is not found unless its current contents
+	// still have that fingerprint.
+	Fingerprints *resources.Fingerprints
+}
+
+// immutable is the Cache-Control of fingerprinted resources.
+const immutable = "public, max-age=31536000, immutable"
+
+// NewServer returns a Server for the resources of b.
+func NewServer(b resources.Bundle) *Server {
+	return &Server{Bundle: b}
+}
+
+// acceptsEncoding reports whether the request's Accept-Encoding header
+// allows the named encoding, with a non-zero q-value given for it, or
+// for "*" if it isn't named.
+func acceptsEncoding(r *http.Request, name string) bool {
+	accepted, star := false, false
+	found, found_star := false, false
+	for _, field := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(field), ";")
+		coding = strings.TrimSpace(coding)
+		q := 1.0
+		for _, param := range strings.Split(params, ";") {
+			key, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if strings.EqualFold(strings.TrimSpace(key), "q") {
+				var err error
+				if q, err = strconv.ParseFloat(strings.TrimSpace(value), 64); err != nil {
+					q = 0
+				}
+			}
+		}
+		switch {
+		case strings.EqualFold(coding, name):
+			accepted, found = q > 0, true
+		case coding == "*":
+			star, found_star = q > 0, true
+		}
+	}
+	if found {
+		return accepted
+	}
+	return found_star && star
+}
+
+// open opens the resource at p, or its compressed form if enabled
+// and accepted, returning the Content-Encoding used. Its FileInfo is
+// returned too, if the bundle is a Searcher and it can be Stat'ed.
+func (s *Server) open(r *http.Request, p string) (io.ReadCloser, os.FileInfo, string, error) {
+	if s.Precompressed {
+		for _, enc := range resources.Encodings() {
+			if !acceptsEncoding(r, enc.Name) {
+				continue
+			}
+			rdr, info, err := s.openResource(p + enc.Ext)
+			if err == nil {
+				return rdr, info, enc.Name, nil
+			} else if err != resources.ErrNotFound {
+				return nil, nil, "", err
+			}
+		}
+	}
+	rdr, info, err := s.openResource(p)
+	return rdr, info, "", err
+}
+
+func (s *Server) openResource(p string) (io.ReadCloser, os.FileInfo, error) {
+	searcher, ok := s.Bundle.(resources.Searcher)
+	if !ok {
+		rdr, err := s.Bundle.Open(p)
+		return rdr, nil, err
+	}
+	rsrc, err := searcher.Find(p)
+	if err != nil {
+		return nil, nil, err
+	}
+	rdr, err := rsrc.Open()
+	if err != nil {
+		return nil, nil, err
+	}
+	info, err := rsrc.Stat()
+	if err != nil {
+		info = nil
+	}
+	return rdr, info, nil
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" && r.Method != "HEAD" {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	p := strings.TrimPrefix(r.URL.Path, "/")
+	if err := resources.CheckPath(p); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	fingerprinted := false
+	if s.Fingerprints != nil {
+		if logical, ok := s.Fingerprints.Logical(p); ok {
+			current, err := resources.FingerprintPath(s.Bundle, logical)
+			if err == resources.ErrNotFound || (err == nil && current != p) {
+				http.NotFound(w, r)
+				return
+			} else if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			p, fingerprinted = logical, true
+		}
+	}
+
+	rdr, info, encoding, err := s.open(r, p)
+	if err == resources.ErrNotFound {
+		http.NotFound(w, r)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer rdr.Close()
+
+	header := w.Header()
+	if s.Precompressed {
+		header.Add("Vary", "Accept-Encoding")
+	}
+	if fingerprinted {
+		header.Set("Cache-Control", immutable)
+	}
+	if encoding != "" {
+		header.Set("Content-Encoding", encoding)
+	}
+	if ctype := mime.TypeByExtension(path.Ext(p)); ctype != "" {
+		header.Set("Content-Type", ctype)
+	} else if encoding != "" {
+		// Don't let the compressed bytes be sniffed.
+		header.Set("Content-Type", "application/octet-stream")
+	}
+
+	var modtime time.Time
+	if info != nil {
+		modtime = info.ModTime()
+		header.Set("Etag", fmt.Sprintf(`W/"%x-%x"`, info.Size(), modtime.UnixNano()))
+	}
+
+	// Readers which can seek get Range and conditional requests
+	// handled by http.ServeContent.
+	if seeker, ok := rdr.(io.ReadSeeker); ok {
+		http.ServeContent(w, r, p, modtime, seeker)
+		return
+	}
+	if info != nil {
+		if !modtime.IsZero() {
+			header.Set("Last-Modified", modtime.UTC().Format(http.TimeFormat))
+		}
+		header.Set("Content-Length", strconv.FormatInt(info.Size(), 10))
+	}
+	if r.Method == "GET" {
+		io.Copy(w, rdr)
+	}
+}
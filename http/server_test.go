@@ -0,0 +1,90 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	. "testing"
+
+	"gopkg.in/cookieo9/resources-go.v2"
+)
+
+func TestAcceptsEncoding(t *T) {
+	for _, test := range []struct {
+		header string
+		want   bool
+	}{
+		{"gzip", true},
+		{"br, gzip;q=0.5", true},
+		{"gzip;q=0", false},
+		{"gzip; q=0.0", false},
+		{"*, gzip;q=0", false},
+		{"*", true},
+		{"*;q=0", false},
+		{"identity", false},
+		{"", false},
+	} {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Header.Set("Accept-Encoding", test.header)
+		if got := acceptsEncoding(r, "gzip"); got != test.want {
+			t.Errorf("acceptsEncoding(%q, gzip) = %v, want %v", test.header, got, test.want)
+		}
+	}
+}
+
+func TestServerRange(t *T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("0123456789"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	s := NewServer(resources.OpenDir(dir))
+
+	r := httptest.NewRequest("GET", "/a.txt", nil)
+	r.Header.Set("Range", "bytes=2-4")
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	if w.Code != http.StatusPartialContent || w.Body.String() != "234" {
+		t.Errorf("Range response = %d %q, want 206 \"234\"", w.Code, w.Body.String())
+	}
+
+	etag := w.Header().Get("Etag")
+	r = httptest.NewRequest("GET", "/a.txt", nil)
+	r.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	if etag == "" || w.Code != http.StatusNotModified {
+		t.Errorf("If-None-Match %q response = %d, want 304", etag, w.Code)
+	}
+}
+
+func TestServerFingerprints(t *T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "app.js")
+	if err := os.WriteFile(name, []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	b := resources.OpenDir(dir)
+	f, err := resources.NewFingerprints(b.(resources.Lister))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fp, _ := f.Path("app.js")
+	s := &Server{Bundle: b, Fingerprints: f}
+
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", "/"+fp, nil))
+	if w.Code != http.StatusOK || w.Header().Get("Cache-Control") != immutable {
+		t.Errorf("GET %s = %d, Cache-Control %q", fp, w.Code, w.Header().Get("Cache-Control"))
+	}
+
+	// Once the contents change, the old fingerprint mustn't serve them.
+	if err := os.WriteFile(name, []byte("new"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", "/"+fp, nil))
+	if w.Code != http.StatusNotFound || w.Header().Get("Cache-Control") != "" {
+		t.Errorf("GET stale %s = %d, Cache-Control %q, want 404", fp, w.Code, w.Header().Get("Cache-Control"))
+	}
+}
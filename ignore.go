@@ -0,0 +1,249 @@
+package resources
+
+import (
+	"io"
+	"io/ioutil"
+	"path"
+	"strings"
+)
+
+// IgnoreFile is the name of the file, at the root of a bundle, from
+// which OpenIgnoring reads patterns of resources to hide.
+const IgnoreFile = ".resourceignore"
+
+// DefaultIgnore are the patterns hiding source files, version control
+// directories, and editor files from package bundles. Other dot files,
+// such as .well-known, are kept.
+var DefaultIgnore = []string{
+	"*.go",
+	".git", ".hg", ".svn", ".bzr", "CVS",
+	".DS_Store", ".idea", ".vscode",
+	"*~", "*.swp", "#*#", ".#*",
+}
+
+type ignoreRule struct {
+	segments []string // pattern split at slashes, "**" matching any number of them
+	negate   bool
+	dir_only bool
+}
+
+// IgnoreRules are patterns in the syntax of .gitignore files, which
+// select resources to hide:
+//  - blank lines and lines starting with "#" are skipped
+//  - a pattern starting with "!" re-includes what earlier patterns hid,
+//    unless a parent directory of it is hidden
+//  - a pattern ending with "/" only matches directories
+//  - a pattern with a slash at its start or middle matches paths from
+//    the root, others match a name at any depth
+//  - "*", "?" and "[...]" match as with path.Match, and "**" matches
+//    any number of directories, or at the end of a pattern everything
+//    inside a directory
+//
+// A pattern matching a directory hides everything beneath it.
+type IgnoreRules struct {
+	rules []ignoreRule
+}
+
+// ParseIgnore parses patterns, one per line, as in a .gitignore file.
+func ParseIgnore(text string) (*IgnoreRules, error) {
+	ir := new(IgnoreRules)
+	if err := ir.Add(strings.Split(text, "\n")...); err != nil {
+		return nil, err
+	}
+	return ir, nil
+}
+
+// Add adds patterns to the rules, after those already present, so they
+// take precedence.
+func (ir *IgnoreRules) Add(patterns ...string) error {
+	for _, line := range patterns {
+		line = strings.TrimRight(line, " \t\r")
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		var rule ignoreRule
+		if line[0] == '!' {
+			rule.negate, line = true, line[1:]
+		} else if line[0] == '\\' {
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			rule.dir_only, line = true, strings.TrimRight(line, "/")
+		}
+		if !strings.Contains(line, "/") {
+			line = "**/" + line
+		}
+		rule.segments = strings.Split(strings.TrimPrefix(line, "/"), "/")
+		for _, seg := range rule.segments {
+			if _, err := path.Match(seg, ""); err != nil {
+				return err
+			}
+		}
+		ir.rules = append(ir.rules, rule)
+	}
+	return nil
+}
+
+// matchSegments matches a path split at slashes against a pattern.
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			// A trailing "**" matches what is inside a directory,
+			// not the directory itself.
+			first := 0
+			if len(pattern) == 1 {
+				first = 1
+			}
+			for i := first; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if match, _ := path.Match(pattern[0], name[0]); !match {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+// ignores reports whether the last rule matching name hides it.
+func (ir *IgnoreRules) ignores(name []string, is_dir bool) bool {
+	for i := len(ir.rules) - 1; i >= 0; i-- {
+		rule := ir.rules[i]
+		if (is_dir || !rule.dir_only) && matchSegments(rule.segments, name) {
+			return !rule.negate
+		}
+	}
+	return false
+}
+
+// Match reports whether the rules hide the resource at p, either
+// itself or through one of its parent directories.
+func (ir *IgnoreRules) Match(p string) bool {
+	if ir == nil {
+		return false
+	}
+	name := strings.Split(path.Clean(p), "/")
+	for i := 1; i < len(name); i++ {
+		if ir.ignores(name[:i], true) {
+			return true
+		}
+	}
+	return ir.ignores(name, false)
+}
+
+type filteredBundle struct {
+	bundle Bundle
+	rules  *IgnoreRules
+}
+
+// OpenFiltered wraps a bundle so that the resources matched by rules
+// are hidden from Open, Find, Glob and List.
+//
+// The returned Bundle implements Searcher and Lister, which find
+// nothing if the wrapped bundle doesn't implement them. Closing it
+// closes the wrapped bundle.
+func OpenFiltered(b Bundle, rules *IgnoreRules) Bundle {
+	return &filteredBundle{b, rules}
+}
+
+// OpenIgnoring is like OpenFiltered, with rules made from patterns,
+// followed by those in the IgnoreFile of the bundle, if it has one.
+// The IgnoreFile itself is always hidden.
+func OpenIgnoring(b Bundle, patterns ...string) (Bundle, error) {
+	rules := new(IgnoreRules)
+	if err := rules.Add("/" + IgnoreFile); err != nil {
+		return nil, err
+	}
+	if err := rules.Add(patterns...); err != nil {
+		return nil, err
+	}
+	rdr, err := b.Open(IgnoreFile)
+	if err == nil {
+		data, err := ioutil.ReadAll(rdr)
+		rdr.Close()
+		if err != nil {
+			return nil, err
+		}
+		if err := rules.Add(strings.Split(string(data), "\n")...); err != nil {
+			return nil, err
+		}
+	} else if err != ErrNotFound {
+		return nil, err
+	}
+	return OpenFiltered(b, rules), nil
+}
+
+func (fb *filteredBundle) filter(rsrcs []Resource) []Resource {
+	var kept []Resource
+	for _, rsrc := range rsrcs {
+		if !fb.rules.Match(rsrc.Path()) {
+			kept = append(kept, rsrc)
+		}
+	}
+	return kept
+}
+
+// Bundles filters each layer of the wrapped bundle.
+func (fb *filteredBundle) Bundles() []Bundle {
+	var layers []Bundle
+	for _, layer := range layersOf(fb.bundle) {
+		if layer != nil {
+			layers = append(layers, OpenFiltered(layer, fb.rules))
+		}
+	}
+	return layers
+}
+
+func (fb *filteredBundle) wrapped() Bundle {
+	return fb.bundle
+}
+
+func (fb *filteredBundle) Close() error {
+	return fb.bundle.Close()
+}
+
+func (fb *filteredBundle) Open(path string) (io.ReadCloser, error) {
+	if fb.rules.Match(path) {
+		return nil, ErrNotFound
+	}
+	return fb.bundle.Open(path)
+}
+
+func (fb *filteredBundle) Find(path string) (Resource, error) {
+	searcher, ok := fb.bundle.(Searcher)
+	if !ok || fb.rules.Match(path) {
+		return nil, ErrNotFound
+	}
+	return searcher.Find(path)
+}
+
+func (fb *filteredBundle) Glob(pattern string) ([]Resource, error) {
+	searcher, ok := fb.bundle.(Searcher)
+	if !ok {
+		return nil, nil
+	}
+	rsrcs, err := searcher.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+	return fb.filter(rsrcs), nil
+}
+
+func (fb *filteredBundle) List() ([]Resource, error) {
+	lister, ok := fb.bundle.(Lister)
+	if !ok {
+		return nil, nil
+	}
+	rsrcs, err := lister.List()
+	if err != nil {
+		return nil, err
+	}
+	return fb.filter(rsrcs), nil
+}
@@ -0,0 +1,128 @@
+package resources
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	. "testing"
+)
+
+var filteredBundle_Is_A_Searcher Searcher = &filteredBundle{}
+var filteredBundle_Is_A_Lister Lister = &filteredBundle{}
+
+func TestIgnoreRules(t *T) {
+	rules, err := ParseIgnore(`
+# comment
+*.log
+!keep.log
+build/
+/root.txt
+docs/**/*.tmp
+cache/**
+\#hash
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for p, want := range map[string]bool{
+		"a.log":             true,
+		"sub/a.log":         true,
+		"keep.log":          false,
+		"sub/keep.log":      false,
+		"build/x.js":        true,
+		"sub/build/x.js":    true,
+		"build":             false, // a file, not a directory
+		"root.txt":          true,
+		"sub/root.txt":      false,
+		"docs/a.tmp":        true,
+		"docs/a/b/c.tmp":    true,
+		"other/a.tmp":       false,
+		"#hash":             true,
+		"build/keep.log":    true, // parent directory hidden
+		"readme.txt":        false,
+		"sub/../a.log":      true,
+		"docs/readme.tmp.x": false,
+		"cache":             false, // only what is inside
+		"cache/a/b":         true,
+	} {
+		if got := rules.Match(p); got != want {
+			t.Errorf("Match(%q) = %v, want %v", p, got, want)
+		}
+	}
+
+	if _, err := ParseIgnore("[bad"); err == nil {
+		t.Error("ParseIgnore([bad) succeeded")
+	}
+}
+
+func TestOpenIgnoring(t *T) {
+	dir := t.TempDir()
+	for name, data := range map[string]string{
+		IgnoreFile:       "secret/\n",
+		"main.go":        "package main",
+		".git/HEAD":      "ref",
+		".well-known/id": "id",
+		"index.html":     "<html>",
+		"secret/key":     "shh",
+		"static/app.js~": "old",
+		"static/app.js":  "new",
+	} {
+		full := filepath.Join(dir, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(full), 0755)
+		if err := ioutil.WriteFile(full, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	b, err := OpenIgnoring(OpenDir(dir), DefaultIgnore...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	list, err := b.(Lister).List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Log(list)
+	if len(list) != 3 {
+		t.Errorf("List() = %v, want .well-known/id, index.html and static/app.js", list)
+	}
+	for _, p := range []string{"main.go", ".git/HEAD", "secret/key", IgnoreFile} {
+		if _, err := b.Open(p); err != ErrNotFound {
+			t.Errorf("Open(%s) = %v, want ErrNotFound", p, err)
+		}
+		if _, err := b.(Searcher).Find(p); err != ErrNotFound {
+			t.Errorf("Find(%s) = %v, want ErrNotFound", p, err)
+		}
+	}
+	if got := readAll(t, b, "static/app.js"); got != "new" {
+		t.Errorf("Open(static/app.js) = %q", got)
+	}
+	if matches, _ := b.(Searcher).Glob("static/*"); len(matches) != 1 {
+		t.Errorf("Glob(static/*) = %v, want static/app.js", matches)
+	}
+}
+
+// listBundle lists a fixed slice of resources.
+type listBundle struct {
+	Bundle
+	list []Resource
+}
+
+func (lb *listBundle) List() ([]Resource, error) { return lb.list, nil }
+
+func TestFilterCopies(t *T) {
+	dir := OpenDir(t.TempDir()).(*dirBundle)
+	list := []Resource{dir.file("a.go"), dir.file("b.txt")}
+	b, err := OpenIgnoring(&listBundle{dir, list}, DefaultIgnore...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if kept, _ := b.(Lister).List(); len(kept) != 1 || kept[0].Path() != "b.txt" {
+			t.Errorf("List() = %v, want b.txt", kept)
+		}
+	}
+	if list[0].Path() != "a.go" || list[1].Path() != "b.txt" {
+		t.Errorf("wrapped list changed to %v", list)
+	}
+}
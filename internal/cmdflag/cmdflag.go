@@ -0,0 +1,19 @@
+// Package cmdflag holds flag types shared by the commands.
+package cmdflag
+
+import (
+	"strings"
+)
+
+// Strings is a flag.Value collecting the values of a repeated flag,
+// eg: patterns.
+type Strings []string
+
+func (s *Strings) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *Strings) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
@@ -0,0 +1,26 @@
+// Package match matches resource paths against the patterns of
+// resources.PackOptions, and of the commands' pattern flags.
+package match
+
+import (
+	"path"
+	"strings"
+)
+
+// Any reports whether any of the path.Match patterns match the
+// resource path p. Patterns without a slash match the base name of p,
+// others match the whole of it.
+func Any(patterns []string, p string) (bool, error) {
+	for _, pattern := range patterns {
+		name := p
+		if !strings.Contains(pattern, "/") {
+			name = path.Base(p)
+		}
+		if match, err := path.Match(pattern, name); err != nil {
+			return false, err
+		} else if match {
+			return true, nil
+		}
+	}
+	return false, nil
+}
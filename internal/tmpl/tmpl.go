@@ -0,0 +1,197 @@
+// Package tmpl holds the code shared by the htmltemplate and
+// texttemplate packages.
+package tmpl
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path"
+	"sync"
+	"sync/atomic"
+
+	"gopkg.in/cookieo9/resources-go.v2"
+)
+
+// A Source is the text of a template, named by the base name of the
+// resource it was read from.
+type Source struct {
+	Name string
+	Text string
+}
+
+// layers returns the searchers of the layers of b, and of their
+// layers, highest priority first, or b itself if it isn't layered.
+func layers(b resources.Bundle) []resources.Searcher {
+	var bundles []resources.Bundle
+	if l, ok := b.(resources.Layered); ok {
+		bundles = l.Bundles()
+	}
+	if bundles == nil {
+		if searcher, ok := b.(resources.Searcher); ok {
+			return []resources.Searcher{searcher}
+		}
+		return nil
+	}
+	var searchers []resources.Searcher
+	for _, layer := range bundles {
+		if layer != nil {
+			searchers = append(searchers, layers(layer)...)
+		}
+	}
+	return searchers
+}
+
+// Collect reads the templates matching the patterns in s, in the order
+// of the patterns, then paths, using the first template with each name.
+// If s is resources.Layered, its layers, and theirs, are searched this
+// way one at a time, highest priority first, so a template in one layer
+// overrides those of the same name in later layers, and the templates
+// are in the order of their layers first. The Strategy of a Sequence
+// isn't applied: the layers always override first-wins.
+func Collect(s resources.Searcher, patterns []string) ([]Source, error) {
+	var sources []Source
+	seen := make(map[string]bool)
+	var layered []resources.Searcher
+	if b, ok := s.(resources.Bundle); ok {
+		layered = layers(b)
+	} else {
+		layered = []resources.Searcher{s}
+	}
+	for _, layer := range layered {
+		for _, pattern := range patterns {
+			matches, err := layer.Glob(pattern)
+			if err != nil {
+				return nil, err
+			}
+			for _, rsrc := range matches {
+				name := path.Base(rsrc.Path())
+				if seen[name] {
+					continue
+				}
+				seen[name] = true
+				rdr, err := rsrc.Open()
+				if err != nil {
+					return nil, err
+				}
+				text, err := ioutil.ReadAll(rdr)
+				rdr.Close()
+				if err != nil {
+					return nil, err
+				}
+				sources = append(sources, Source{name, string(text)})
+			}
+		}
+	}
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("resources: no templates match %q", patterns)
+	}
+	return sources, nil
+}
+
+// A Template is a *html/template.Template or *text/template.Template.
+type Template[T any] interface {
+	Name() string
+	New(name string) T
+	Parse(text string) (T, error)
+	Clone() (T, error)
+}
+
+// Parse parses the templates matching the patterns in s into t, as
+// t.ParseGlob does. The templates are read by Collect, and named by
+// the base names of their resources.
+func Parse[T Template[T]](t T, s resources.Searcher, patterns []string) (T, error) {
+	sources, err := Collect(s, patterns)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	for _, src := range sources {
+		tt := t
+		if src.Name != t.Name() {
+			tt = t.New(src.Name)
+		}
+		if _, err := tt.Parse(src.Text); err != nil {
+			var zero T
+			return zero, err
+		}
+	}
+	return t, nil
+}
+
+// NewTemplateReloader returns a Reloader of the templates matching the
+// patterns in s, parsed into a clone of base each time.
+func NewTemplateReloader[T Template[T]](base T, s resources.Searcher, w resources.Watcher, patterns []string) (*Reloader[T], error) {
+	load := func() (T, error) {
+		t, err := base.Clone()
+		if err != nil {
+			return t, err
+		}
+		return Parse(t, s, patterns)
+	}
+	return NewReloader(load, w, patterns)
+}
+
+// A Reloader holds the latest value loaded by a function, which is
+// called again when a watcher reports a change to a path matching one
+// of the patterns.
+type Reloader[T any] struct {
+	load     func() (T, error)
+	patterns []string
+	cancel   func()
+
+	mu  sync.Mutex // serialises loads
+	cur atomic.Pointer[T]
+	err atomic.Pointer[error]
+}
+
+// NewReloader loads the first value, failing if it can't, then watches
+// for changes.
+func NewReloader[T any](load func() (T, error), w resources.Watcher, patterns []string) (*Reloader[T], error) {
+	r := &Reloader[T]{load: load, patterns: patterns}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	r.cancel = w.Watch(r.changed)
+	return r, nil
+}
+
+func (r *Reloader[T]) changed(p string) {
+	for _, pattern := range r.patterns {
+		if match, _ := path.Match(pattern, p); match {
+			r.Reload()
+			return
+		}
+	}
+}
+
+// Reload loads the value now. If it fails, the previous value is kept.
+func (r *Reloader[T]) Reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	v, err := r.load()
+	if err != nil {
+		r.err.Store(&err)
+		return err
+	}
+	r.cur.Store(&v)
+	r.err.Store(nil)
+	return nil
+}
+
+// Value returns the latest value loaded.
+func (r *Reloader[T]) Value() T {
+	return *r.cur.Load()
+}
+
+// Err returns the error of the last load, if it failed.
+func (r *Reloader[T]) Err() error {
+	if err := r.err.Load(); err != nil {
+		return *err
+	}
+	return nil
+}
+
+// Close stops watching for changes.
+func (r *Reloader[T]) Close() {
+	r.cancel()
+}
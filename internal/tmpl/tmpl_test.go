@@ -0,0 +1,45 @@
+package tmpl
+
+import (
+	"os"
+	"path/filepath"
+	. "testing"
+
+	"gopkg.in/cookieo9/resources-go.v2"
+)
+
+func TestCollectLayers(t *T) {
+	dirs := make([]resources.Bundle, 3)
+	for i, text := range []string{"a", "b", "c"} {
+		dir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(dir, "x.tmpl"), []byte(text), 0644); err != nil {
+			t.Fatal(err)
+		}
+		dirs[i] = resources.OpenDir(dir)
+	}
+
+	for _, test := range []struct {
+		name string
+		s    resources.Bundle
+		want string
+	}{
+		{"Sequence", resources.NewSequence(nil, dirs[0], dirs[1]), "a"},
+		{"filtered", must(resources.OpenIgnoring(resources.BundleSequence{dirs[1], dirs[0]})), "b"},
+		{"nested", resources.BundleSequence{resources.NewSearchPath(dirs[2], dirs[0]), dirs[1]}, "c"},
+	} {
+		sources, err := Collect(test.s.(resources.Searcher), []string{"*.tmpl"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(sources) != 1 || sources[0].Text != test.want {
+			t.Errorf("%s: Collect = %v, want x.tmpl from %s", test.name, sources, test.want)
+		}
+	}
+}
+
+func must(b resources.Bundle, err error) resources.Bundle {
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
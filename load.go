@@ -0,0 +1,211 @@
+package resources
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"reflect"
+	"sync"
+)
+
+// ErrNoDecoder is returned by Load for resources whose extension has
+// no registered Decoder.
+var ErrNoDecoder = errors.New("resources: no decoder for extension")
+
+// A Decoder decodes the data read from r into v, which is a pointer.
+type Decoder func(r io.Reader, v any) error
+
+var (
+	decodersMu sync.RWMutex
+	decoders   = map[string]Decoder{
+		".json": func(r io.Reader, v any) error { return json.NewDecoder(r).Decode(v) },
+		".xml":  func(r io.Reader, v any) error { return xml.NewDecoder(r).Decode(v) },
+		".gob":  func(r io.Reader, v any) error { return gob.NewDecoder(r).Decode(v) },
+	}
+)
+
+// RegisterDecoder sets the Decoder used by Load for resources with the
+// extension ext, including the leading dot, eg: ".toml". Decoders for
+// ".json", ".xml" and ".gob" are built in.
+func RegisterDecoder(ext string, dec Decoder) {
+	decodersMu.Lock()
+	defer decodersMu.Unlock()
+	decoders[ext] = dec
+}
+
+func decoderFor(p string) (Decoder, error) {
+	decodersMu.RLock()
+	defer decodersMu.RUnlock()
+	if dec, ok := decoders[path.Ext(p)]; ok {
+		return dec, nil
+	}
+	return nil, fmt.Errorf("%w %q: %s", ErrNoDecoder, path.Ext(p), p)
+}
+
+func decode[T any](open func() (io.ReadCloser, error), p string) (T, error) {
+	var v T
+	dec, err := decoderFor(p)
+	if err != nil {
+		return v, err
+	}
+	rdr, err := open()
+	if err != nil {
+		return v, err
+	}
+	defer rdr.Close()
+	if err := dec(rdr, &v); err != nil {
+		return v, fmt.Errorf("resources: decoding %s: %w", p, err)
+	}
+	return v, nil
+}
+
+// Load decodes the resource at path in b as a T, with the Decoder
+// registered for its extension.
+func Load[T any](b Bundle, path string) (T, error) {
+	return decode[T](func() (io.ReadCloser, error) { return b.Open(path) }, path)
+}
+
+// MustLoad is like Load, but panics if the resource can't be loaded.
+// It simplifies loading resources which are part of a program, eg: to
+// initialize global variables.
+func MustLoad[T any](b Bundle, path string) T {
+	v, err := Load[T](b, path)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+type loadKey struct {
+	path string
+	typ  reflect.Type
+}
+
+type loadEntry struct {
+	info  os.FileInfo
+	sum   []byte // hash of the contents, if info doesn't identify them
+	value any
+}
+
+// identifies reports whether info identifies the contents of a
+// resource, together with its modification time and size: it is of a
+// file, or of a zip file entry, with its checksum.
+func identifies(info os.FileInfo) bool {
+	if _, ok := info.Sys().(*zip.FileHeader); ok {
+		return true
+	}
+	return os.SameFile(info, info)
+}
+
+// sameContents reports whether the resources described by old and info
+// are known to have the same contents, without reading them.
+func sameContents(old, info os.FileInfo) bool {
+	if !old.ModTime().Equal(info.ModTime()) || old.Size() != info.Size() {
+		return false
+	}
+	if oh, ok := old.Sys().(*zip.FileHeader); ok {
+		h, ok := info.Sys().(*zip.FileHeader)
+		return ok && h.CRC32 == oh.CRC32
+	}
+	return os.SameFile(old, info)
+}
+
+// hashOf returns a hash of the contents of rsrc.
+func hashOf(rsrc Resource) ([]byte, error) {
+	rdr, err := rsrc.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rdr.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, rdr); err != nil {
+		return nil, err
+	}
+	return hash.Sum(nil), nil
+}
+
+// A LoadCache keeps the values loaded from the resources of a bundle
+// by LoadCached, until the resources change. A resource is unchanged if
+// its modification time and size are the same, and it is the same
+// file, or a zip file entry with the same checksum; so a resource
+// replaced by another of the same size, eg: in an added layer or a
+// deterministic zip file, is loaded again. The contents of other
+// resources are hashed to see if they changed, which saves decoding
+// them again.
+type LoadCache struct {
+	bundle Bundle
+	mu     sync.Mutex
+	values map[loadKey]*loadEntry
+}
+
+// NewLoadCache returns a LoadCache for the resources of b. Without a
+// Searcher to see if resources change, nothing is cached.
+func NewLoadCache(b Bundle) *LoadCache {
+	return &LoadCache{bundle: b, values: make(map[loadKey]*loadEntry)}
+}
+
+// Invalidate forgets the values loaded from the resource at path.
+func (lc *LoadCache) Invalidate(path string) {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	for key := range lc.values {
+		if key.path == path {
+			delete(lc.values, key)
+		}
+	}
+}
+
+// LoadCached is like Load, but returns the value previously loaded as
+// a T from the resource, if it hasn't changed. The value is shared by
+// all callers, so it mustn't be modified.
+func LoadCached[T any](lc *LoadCache, path string) (T, error) {
+	searcher, ok := lc.bundle.(Searcher)
+	if !ok {
+		return Load[T](lc.bundle, path)
+	}
+	rsrc, err := searcher.Find(path)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	info, err := rsrc.Stat()
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+
+	key := loadKey{path, reflect.TypeOf((*T)(nil)).Elem()}
+	lc.mu.Lock()
+	entry, ok := lc.values[key]
+	lc.mu.Unlock()
+	if ok && sameContents(entry.info, info) {
+		return entry.value.(T), nil
+	}
+	var sum []byte
+	if !identifies(info) {
+		if sum, err = hashOf(rsrc); err != nil {
+			var zero T
+			return zero, err
+		}
+		if ok && entry.sum != nil && bytes.Equal(entry.sum, sum) {
+			return entry.value.(T), nil
+		}
+	}
+
+	v, err := decode[T](rsrc.Open, path)
+	if err != nil {
+		return v, err
+	}
+	lc.mu.Lock()
+	lc.values[key] = &loadEntry{info, sum, v}
+	lc.mu.Unlock()
+	return v, nil
+}
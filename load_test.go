@@ -0,0 +1,132 @@
+package resources
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	. "testing"
+	"time"
+)
+
+type loadConfig struct {
+	Name  string `json:"name" xml:"name"`
+	Count int    `json:"count" xml:"count"`
+}
+
+func TestLoad(t *T) {
+	gobData := new(bytes.Buffer)
+	if err := gob.NewEncoder(gobData).Encode(loadConfig{"gob", 3}); err != nil {
+		t.Fatal(err)
+	}
+	b := zipOfFiles(t, map[string]string{
+		"config.json": `{"name": "json", "count": 1}`,
+		"config.xml":  `<config><name>xml</name><count>2</count></config>`,
+		"config.gob":  gobData.String(),
+		"config.ini":  `name=ini`,
+		"bad.json":    `{`,
+		"words.txt":   "a b c",
+	})
+
+	for p, want := range map[string]loadConfig{
+		"config.json": {"json", 1},
+		"config.xml":  {"xml", 2},
+		"config.gob":  {"gob", 3},
+	} {
+		got, err := Load[loadConfig](b, p)
+		if err != nil {
+			t.Errorf("Load(%s): %v", p, err)
+		} else if got != want {
+			t.Errorf("Load(%s) = %+v, want %+v", p, got, want)
+		}
+	}
+
+	if _, err := Load[loadConfig](b, "config.ini"); !errors.Is(err, ErrNoDecoder) {
+		t.Errorf("Load(config.ini) = %v, want ErrNoDecoder", err)
+	}
+	if _, err := Load[loadConfig](b, "bad.json"); err == nil {
+		t.Error("Load(bad.json) succeeded")
+	}
+	if _, err := Load[loadConfig](b, "missing.json"); err != ErrNotFound {
+		t.Errorf("Load(missing.json) = %v, want ErrNotFound", err)
+	}
+
+	RegisterDecoder(".txt", func(r io.Reader, v any) error {
+		data, err := io.ReadAll(r)
+		*v.(*[]string) = strings.Fields(string(data))
+		return err
+	})
+	if words := MustLoad[[]string](b, "words.txt"); len(words) != 3 {
+		t.Errorf("MustLoad(words.txt) = %v", words)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("MustLoad(missing.json) didn't panic")
+		}
+	}()
+	MustLoad[loadConfig](b, "missing.json")
+}
+
+func TestLoadCached(t *T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "config.json")
+	write := func(data string, modtime time.Time) {
+		if err := os.WriteFile(file, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		os.Chtimes(file, modtime, modtime)
+	}
+	start := time.Now().Add(-time.Hour)
+	write(`{"name": "a"}`, start)
+
+	decodes := 0
+	RegisterDecoder(".counted", func(r io.Reader, v any) error {
+		decodes++
+		return nil
+	})
+	os.WriteFile(filepath.Join(dir, "x.counted"), nil, 0644)
+
+	lc := NewLoadCache(OpenDir(dir))
+	for i := 0; i < 3; i++ {
+		if _, err := LoadCached[loadConfig](lc, "x.counted"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if decodes != 1 {
+		t.Errorf("decoded %d times, want 1", decodes)
+	}
+	if _, err := LoadCached[map[string]any](lc, "x.counted"); err != nil || decodes != 2 {
+		t.Errorf("loading as another type: %v, %d decodes, want 2", err, decodes)
+	}
+
+	if cfg, err := LoadCached[loadConfig](lc, "config.json"); err != nil || cfg.Name != "a" {
+		t.Fatalf("LoadCached(config.json) = %+v, %v", cfg, err)
+	}
+	write(`{"name": "b"}`, start.Add(time.Minute))
+	if cfg, _ := LoadCached[loadConfig](lc, "config.json"); cfg.Name != "b" {
+		t.Errorf("LoadCached(config.json) after change = %+v", cfg)
+	}
+	lc.Invalidate("x.counted")
+	LoadCached[loadConfig](lc, "x.counted")
+	if decodes != 3 {
+		t.Errorf("decoded %d times after Invalidate, want 3", decodes)
+	}
+}
+
+func TestLoadCachedOverride(t *T) {
+	// Entries of zip files made by zipOfFiles have the same, zero
+	// modification time, as in deterministic packs.
+	sp := NewSearchPath(zipOfFiles(t, map[string]string{"config.json": `{"name": "a"}`}))
+	lc := NewLoadCache(sp)
+	if cfg, err := LoadCached[loadConfig](lc, "config.json"); err != nil || cfg.Name != "a" {
+		t.Fatalf("LoadCached(config.json) = %+v, %v", cfg, err)
+	}
+	sp.Prepend(zipOfFiles(t, map[string]string{"config.json": `{"name": "b"}`}))
+	if cfg, _ := LoadCached[loadConfig](lc, "config.json"); cfg.Name != "b" {
+		t.Errorf("LoadCached(config.json) after a same size override = %+v", cfg)
+	}
+}
@@ -0,0 +1,308 @@
+package resources
+
+import (
+	"io"
+	"path"
+	"sort"
+	"strings"
+)
+
+// A LocaleLayout is where the localized forms of resources are stored.
+type LocaleLayout int
+
+const (
+	// LocaleDir stores localized resources in a directory named by
+	// their locale, eg: "fr-CA/strings.json".
+	LocaleDir LocaleLayout = iota
+
+	// LocaleSuffix stores localized resources beside the default,
+	// with their locale before the extension, eg:
+	// "strings.fr-CA.json".
+	LocaleSuffix
+)
+
+// canonicalTag formats a BCP-47 language tag as it's usually written,
+// eg: "zh-Hant-TW", dropping any extensions and private use subtags.
+func canonicalTag(tag string) string {
+	subtags := strings.Split(strings.Replace(tag, "_", "-", -1), "-")
+	for i, sub := range subtags {
+		switch {
+		case i > 0 && len(sub) == 1:
+			// An extension or private use singleton.
+			return strings.Join(subtags[:i], "-")
+		case i == 0:
+			subtags[i] = strings.ToLower(sub)
+		case len(sub) == 4 && sub[0] > '9':
+			subtags[i] = strings.ToUpper(sub[:1]) + strings.ToLower(sub[1:])
+		case len(sub) == 2 || len(sub) == 3 && sub[0] <= '9':
+			subtags[i] = strings.ToUpper(sub)
+		default:
+			subtags[i] = strings.ToLower(sub)
+		}
+	}
+	return strings.Join(subtags, "-")
+}
+
+// LocaleFallbacks returns the locales to search for each of the
+// BCP-47 language tags, in order of preference, each tag being followed
+// by its less specific forms, eg: "fr-CA", "en-GB" gives "fr-CA", "fr",
+// "en-GB", "en". Duplicates, and the "und" (undetermined) tag, are
+// removed.
+func LocaleFallbacks(tags ...string) []string {
+	var chain []string
+	seen := make(map[string]bool)
+	for _, tag := range tags {
+		tag = canonicalTag(tag)
+		for tag != "" && tag != "und" {
+			if !seen[tag] {
+				seen[tag] = true
+				chain = append(chain, tag)
+			}
+			i := strings.LastIndex(tag, "-")
+			if i < 0 {
+				break
+			}
+			tag = tag[:i]
+		}
+	}
+	return chain
+}
+
+// localizedPath returns the path of the form of p for the locale.
+func (layout LocaleLayout) localizedPath(p, locale string) string {
+	if layout == LocaleDir {
+		return locale + "/" + p
+	}
+	ext := path.Ext(p)
+	if ext == path.Base(p) {
+		ext = "" // eg: ".profile"
+	}
+	return p[:len(p)-len(ext)] + "." + locale + ext
+}
+
+// split returns the path and locale of a localized path, if it is a
+// localized form for one of the locales.
+func (layout LocaleLayout) split(p string, locales map[string]bool) (string, string, bool) {
+	if layout == LocaleDir {
+		if locale, rest, ok := strings.Cut(p, "/"); ok && locales[locale] {
+			return rest, locale, true
+		}
+		return p, "", false
+	}
+	dir, base := path.Split(p)
+	ext := path.Ext(base)
+	stem := base[:len(base)-len(ext)]
+	if i := strings.LastIndex(stem, "."); i > 0 && locales[stem[i+1:]] {
+		return dir + stem[:i] + ext, stem[i+1:], true
+	}
+	// Resources without an extension, eg: "README.fr".
+	if i := strings.LastIndex(base, "."); i > 0 && locales[base[i+1:]] {
+		return dir + base[:i], base[i+1:], true
+	}
+	return p, "", false
+}
+
+// localizedResource is the form of a resource chosen for a locale.
+type localizedResource struct {
+	Resource
+	path   string
+	locale string
+}
+
+func (lr *localizedResource) Path() string {
+	return lr.path
+}
+
+func (lr *localizedResource) String() string {
+	return lr.path
+}
+
+// Locale returns the locale of the form of the resource which was
+// chosen, or "" if it is the default form.
+func (lr *localizedResource) Locale() string {
+	return lr.locale
+}
+
+// A Localized bundle uses the form of each resource for the most
+// preferred of its locales which has one, or the resource itself (the
+// default) if none do.
+//
+// Resources it finds implement
+//
+//	interface{ Locale() string }
+//
+// returning the locale of the form which was chosen, or "" for the
+// default.
+//
+// It implements Searcher and Lister, which find nothing if the
+// wrapped bundle doesn't implement them. Listing returns the resources
+// by their unlocalized paths, once each, with the form they would be
+// opened with. Forms for locales not in its chain aren't recognised as
+// such, so are listed by their own paths. Closing it closes the
+// wrapped bundle.
+type Localized struct {
+	bundle  Bundle
+	layout  LocaleLayout
+	chain   []string
+	locales map[string]bool
+}
+
+// OpenLocalized wraps b to use the forms of its resources for the
+// language tags, in order of preference, and the less specific forms
+// of those tags, as given by LocaleFallbacks.
+func OpenLocalized(b Bundle, layout LocaleLayout, tags ...string) *Localized {
+	l := &Localized{bundle: b, layout: layout, chain: LocaleFallbacks(tags...)}
+	l.locales = make(map[string]bool, len(l.chain))
+	for _, locale := range l.chain {
+		l.locales[locale] = true
+	}
+	return l
+}
+
+// Locales returns the locales searched, in order.
+func (l *Localized) Locales() []string {
+	return append([]string(nil), l.chain...)
+}
+
+// OpenLocale opens the preferred form of the resource at path, and
+// returns its locale, or "" for the default.
+func (l *Localized) OpenLocale(path string) (io.ReadCloser, string, error) {
+	for _, locale := range l.chain {
+		rdr, err := l.bundle.Open(l.layout.localizedPath(path, locale))
+		if err == nil {
+			return rdr, locale, nil
+		} else if err != ErrNotFound {
+			return nil, "", err
+		}
+	}
+	rdr, err := l.bundle.Open(path)
+	return rdr, "", err
+}
+
+// FindLocale finds the preferred form of the resource at path, and
+// returns its locale, or "" for the default.
+func (l *Localized) FindLocale(path string) (Resource, string, error) {
+	searcher, ok := l.bundle.(Searcher)
+	if !ok {
+		return nil, "", ErrNotFound
+	}
+	for _, locale := range l.chain {
+		rsrc, err := searcher.Find(l.layout.localizedPath(path, locale))
+		if err == nil {
+			return &localizedResource{rsrc, path, locale}, locale, nil
+		} else if err != ErrNotFound {
+			return nil, "", err
+		}
+	}
+	rsrc, err := searcher.Find(path)
+	if err != nil {
+		return nil, "", err
+	}
+	return &localizedResource{rsrc, path, ""}, "", nil
+}
+
+func (l *Localized) wrapped() Bundle {
+	return l.bundle
+}
+
+func (l *Localized) Close() error {
+	return l.bundle.Close()
+}
+
+func (l *Localized) Open(path string) (io.ReadCloser, error) {
+	rdr, _, err := l.OpenLocale(path)
+	return rdr, err
+}
+
+func (l *Localized) Find(path string) (Resource, error) {
+	rsrc, _, err := l.FindLocale(path)
+	return rsrc, err
+}
+
+// Glob matches the pattern against the unlocalized paths of the
+// resources, returning the form of each which Find returns. Without a
+// Lister, the resources are found by globbing the pattern, and its
+// localized forms for each of the locales.
+func (l *Localized) Glob(pattern string) ([]Resource, error) {
+	if _, ok := l.bundle.(Lister); ok {
+		list, err := l.List()
+		if err != nil {
+			return nil, err
+		}
+		return matchResources(pattern, list)
+	}
+	searcher, ok := l.bundle.(Searcher)
+	if !ok {
+		return nil, nil
+	}
+
+	patterns := []string{pattern}
+	for _, locale := range l.chain {
+		patterns = append(patterns, l.layout.localizedPath(pattern, locale))
+	}
+	seen := make(map[string]bool)
+	var matches []Resource
+	for _, pat := range patterns {
+		found, err := searcher.Glob(pat)
+		if err != nil {
+			return nil, err
+		}
+		for _, rsrc := range found {
+			p, _, _ := l.layout.split(rsrc.Path(), l.locales)
+			if seen[p] {
+				continue
+			}
+			seen[p] = true
+			if match, err := path.Match(pattern, p); err != nil {
+				return nil, err
+			} else if !match {
+				continue
+			}
+			form, _, err := l.FindLocale(p)
+			if err == ErrNotFound {
+				continue
+			} else if err != nil {
+				return nil, err
+			}
+			matches = append(matches, form)
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		return matches[i].Path() < matches[j].Path()
+	})
+	return matches, nil
+}
+
+func (l *Localized) List() ([]Resource, error) {
+	lister, ok := l.bundle.(Lister)
+	if !ok {
+		return nil, nil
+	}
+	rsrcs, err := lister.List()
+	if err != nil {
+		return nil, err
+	}
+
+	rank := make(map[string]int, len(l.chain))
+	for i, locale := range l.chain {
+		rank[locale] = i
+	}
+	best := make(map[string]*localizedResource)
+	var order []string
+	for _, rsrc := range rsrcs {
+		p, locale, localized := l.layout.split(rsrc.Path(), l.locales)
+		prev, ok := best[p]
+		if !ok {
+			order = append(order, p)
+		} else if prev.locale != "" && (!localized || rank[locale] > rank[prev.locale]) {
+			continue
+		}
+		best[p] = &localizedResource{rsrc, p, locale}
+	}
+
+	list := make([]Resource, len(order))
+	for i, p := range order {
+		list[i] = best[p]
+	}
+	return list, nil
+}
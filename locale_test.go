@@ -0,0 +1,106 @@
+package resources
+
+import (
+	"reflect"
+	. "testing"
+)
+
+var Localized_Is_A_Bundle Bundle = &Localized{}
+var Localized_Is_A_Searcher Searcher = &Localized{}
+var Localized_Is_A_Lister Lister = &Localized{}
+
+func TestLocaleFallbacks(t *T) {
+	got := LocaleFallbacks("fr_ca", "zh-hant-tw-u-nu-hanidec", "fr", "und", "es-419")
+	want := []string{"fr-CA", "fr", "zh-Hant-TW", "zh-Hant", "zh", "es-419", "es"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("LocaleFallbacks() = %v, want %v", got, want)
+	}
+}
+
+func TestLocalized(t *T) {
+	layouts := map[LocaleLayout]map[string]string{
+		LocaleDir: {
+			"strings.json":       "default",
+			"fr/strings.json":    "fr",
+			"fr-CA/strings.json": "fr-CA",
+			"fr/logo.png":        "fr logo",
+			"de/strings.json":    "de",
+			"img/only.png":       "only",
+		},
+		LocaleSuffix: {
+			"strings.json":       "default",
+			"strings.fr.json":    "fr",
+			"strings.fr-CA.json": "fr-CA",
+			"logo.fr.png":        "fr logo",
+			"strings.de.json":    "de",
+			"img/only.png":       "only",
+		},
+	}
+	for layout, files := range layouts {
+		b := zipOfFiles(t, files)
+		l := OpenLocalized(b, layout, "fr-CA", "en")
+		t.Log(layout, l.Locales())
+
+		for p, want := range map[string]string{
+			"strings.json": "fr-CA",
+			"logo.png":     "fr logo",
+			"img/only.png": "only",
+		} {
+			if got := readAll(t, l, p); got != want {
+				t.Errorf("%v: Open(%s) = %q, want %q", layout, p, got, want)
+			}
+		}
+
+		rsrc, locale, err := l.FindLocale("logo.png")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if locale != "fr" || rsrc.Path() != "logo.png" || rsrc.(interface{ Locale() string }).Locale() != "fr" {
+			t.Errorf("%v: FindLocale(logo.png) = %s, %q", layout, rsrc.Path(), locale)
+		}
+		if _, locale, _ := l.OpenLocale("img/only.png"); locale != "" {
+			t.Errorf("%v: OpenLocale(img/only.png) locale = %q, want default", layout, locale)
+		}
+
+		list, err := l.List()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := paths(list)
+		t.Log(layout, got)
+		var want []string
+		if layout == LocaleDir {
+			want = []string{"de/strings.json", "img/only.png", "logo.png", "strings.json"}
+		} else {
+			want = []string{"img/only.png", "logo.png", "strings.de.json", "strings.json"}
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%v: List() = %v, want %v", layout, got, want)
+		}
+		for _, rsrc := range list {
+			if rsrc.Path() == "strings.json" && rsrc.(interface{ Locale() string }).Locale() != "fr-CA" {
+				t.Errorf("%v: List() chose %s locale for strings.json", layout, rsrc.(interface{ Locale() string }).Locale())
+			}
+		}
+
+		// Without a Lister, Glob still finds the forms Find would.
+		l = OpenLocalized(searchBundle{b, b.(Searcher)}, layout, "fr-CA", "en")
+		for pattern, want := range map[string]map[string]string{
+			"*.png":        {"logo.png": "fr"},
+			"strings.json": {"strings.json": "fr-CA"},
+			"img/*":        {"img/only.png": ""},
+		} {
+			matches, err := l.Glob(pattern)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := make(map[string]string)
+			for _, rsrc := range matches {
+				got[rsrc.Path()] = rsrc.(interface{ Locale() string }).Locale()
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("%v: Glob(%s) without a Lister = %v, want %v", layout, pattern, got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package resources
+
+import (
+	"reflect"
+	"sync"
+	"time"
+)
+
+// lookupKey is a path looked up by Open or Find, which may find it in
+// different layers, as only Open uses layers which aren't Searchers.
+type lookupKey struct {
+	op   Op
+	path string
+}
+
+// lookupEntry is where a path was found in a Sequence.
+type lookupEntry struct {
+	layer   int // -1 if in none of them
+	expires time.Time
+}
+
+// A LookupCache remembers in which layer of a Sequence each path was
+// found, or that it wasn't found in any, so that finding or opening it
+// again only searches that layer, or none. This avoids searching every
+// layer for resources which are often missing, such as optional
+// overrides. A SearchPath, such as the default one, can be given one
+// with Configure.
+//
+// Nothing is remembered when a layer fails, and where a path was found
+// is only remembered and used if the Sequence has no Strategy, as
+// another Strategy could choose a resource added to another layer
+// since. A cache is for the layers of one Sequence: when it is used
+// with another slice of layers, eg: after the bundles of a SearchPath
+// change, it forgets everything. A path found in a layer is searched
+// for again if that layer no longer has it, but the cache can't
+// otherwise know that a resource was added to a layer: entries have to
+// expire, or be invalidated. Layers which implement Watcher (see
+// Watched) are watched from when the cache is first used with them
+// until it is closed, invalidating the paths they report.
+type LookupCache struct {
+	ttl time.Duration
+
+	mu      sync.Mutex
+	entries map[lookupKey]lookupEntry
+	layers  []Bundle          // the layers the entries are for
+	gen     uint64            // incremented by every invalidation
+	watched map[Bundle]func() // layers watched, and how to stop
+	closed  bool
+}
+
+// NewLookupCache returns a LookupCache whose entries expire after ttl,
+// or never if it is zero.
+func NewLookupCache(ttl time.Duration) *LookupCache {
+	return &LookupCache{
+		ttl:     ttl,
+		entries: make(map[lookupKey]lookupEntry),
+		watched: make(map[Bundle]func()),
+	}
+}
+
+// Invalidate forgets where the paths were found.
+func (lc *LookupCache) Invalidate(paths ...string) {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	lc.gen++
+	for _, path := range paths {
+		delete(lc.entries, lookupKey{OpOpen, path})
+		delete(lc.entries, lookupKey{OpFind, path})
+	}
+}
+
+// InvalidateAll empties the cache.
+func (lc *LookupCache) InvalidateAll() {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	lc.gen++
+	lc.entries = make(map[lookupKey]lookupEntry)
+}
+
+// InvalidateOn invalidates the paths which w reports as changed, until
+// cancel is called. w may watch a single layer, as a layer's paths are
+// the Sequence's.
+func (lc *LookupCache) InvalidateOn(w Watcher) (cancel func()) {
+	return w.Watch(func(path string) {
+		lc.Invalidate(path)
+	})
+}
+
+// watch starts watching the layers implementing Watcher which aren't
+// watched yet. Layers of uncomparable types aren't watched.
+func (lc *LookupCache) watch(layers []Bundle) {
+	if lc == nil {
+		return
+	}
+	for _, layer := range layers {
+		w, ok := layer.(Watcher)
+		if !ok || !reflect.TypeOf(layer).Comparable() {
+			continue
+		}
+		lc.mu.Lock()
+		_, seen := lc.watched[layer]
+		start := !seen && !lc.closed
+		if start {
+			lc.watched[layer] = nil
+		}
+		lc.mu.Unlock()
+		if !start {
+			continue
+		}
+
+		cancel := lc.InvalidateOn(w)
+		lc.mu.Lock()
+		closed := lc.closed
+		if !closed {
+			lc.watched[layer] = cancel
+		}
+		lc.mu.Unlock()
+		if closed {
+			cancel()
+		}
+	}
+}
+
+// Close stops watching the layers. The cache can still be used, but no
+// more layers are watched.
+func (lc *LookupCache) Close() {
+	lc.mu.Lock()
+	watched := lc.watched
+	lc.watched, lc.closed = nil, true
+	lc.mu.Unlock()
+	for _, cancel := range watched {
+		if cancel != nil {
+			cancel()
+		}
+	}
+}
+
+// sameLayers reports whether a and b are the same slice of layers, not
+// merely equal ones.
+func sameLayers(a, b []Bundle) bool {
+	return len(a) == len(b) && (len(a) == 0 || &a[0] == &b[0])
+}
+
+// lookup returns the layer of layers path was found in by op, if it is
+// known, and the generation to store the result of searching for it
+// with. If layers aren't those the entries are for, they are forgotten.
+// It may be called on a nil LookupCache.
+func (lc *LookupCache) lookup(op Op, path string, layers []Bundle) (layer int, gen uint64, ok bool) {
+	if lc == nil {
+		return 0, 0, false
+	}
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	if !sameLayers(layers, lc.layers) {
+		lc.gen++
+		lc.entries = make(map[lookupKey]lookupEntry)
+		lc.layers = layers
+	}
+	key := lookupKey{op, path}
+	entry, ok := lc.entries[key]
+	if ok && lc.ttl > 0 && time.Now().After(entry.expires) {
+		delete(lc.entries, key)
+		ok = false
+	}
+	return entry.layer, lc.gen, ok
+}
+
+// store remembers the layer path was found in by op, unless the cache
+// has been invalidated since gen.
+func (lc *LookupCache) store(op Op, path string, layer int, gen uint64) {
+	if lc == nil {
+		return
+	}
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	if gen != lc.gen {
+		return
+	}
+	entry := lookupEntry{layer: layer}
+	if lc.ttl > 0 {
+		entry.expires = time.Now().Add(lc.ttl)
+	}
+	lc.entries[lookupKey{op, path}] = entry
+}
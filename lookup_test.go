@@ -0,0 +1,142 @@
+package resources
+
+import (
+	. "testing"
+	"time"
+)
+
+func TestSequenceLookups(t *T) {
+	top := &countBundle{contents: map[string]string{}}
+	bottom := &countBundle{contents: map[string]string{"x": "bottom"}}
+	s := &Sequence{Layers: BundleSequence{top, bottom}, Lookups: NewLookupCache(0)}
+	opens := func() (int32, int32) {
+		defer func() { top.opens, bottom.opens = 0, 0 }()
+		return top.opens, bottom.opens
+	}
+
+	// A miss is only searched for once.
+	for i := 0; i < 3; i++ {
+		if _, err := s.Open("override"); err != ErrNotFound {
+			t.Fatalf("Open(override) = %v, want ErrNotFound", err)
+		}
+	}
+	if a, b := opens(); a != 1 || b != 1 {
+		t.Errorf("opens after misses = %d, %d, want 1, 1", a, b)
+	}
+
+	// A hit only searches the layer it was found in.
+	readAll(t, s, "x")
+	readAll(t, s, "x")
+	if a, b := opens(); a != 1 || b != 2 {
+		t.Errorf("opens after hits = %d, %d, want 1, 2", a, b)
+	}
+
+	// Changes reported by a watcher are seen.
+	w := &funcWatcher{}
+	cancel := s.Lookups.InvalidateOn(w)
+	defer cancel()
+	top.contents["override"] = "top"
+	top.contents["x"] = "top"
+	w.fn("override")
+	w.fn("x")
+	if data := readAll(t, s, "override"); data != "top" {
+		t.Errorf("Open(override) = %q, want top", data)
+	}
+	if data := readAll(t, s, "x"); data != "top" {
+		t.Errorf("Open(x) = %q, want top", data)
+	}
+
+	// A found resource which goes away is searched for again.
+	delete(top.contents, "x")
+	if data := readAll(t, s, "x"); data != "bottom" {
+		t.Errorf("Open(x) = %q, want bottom", data)
+	}
+
+	// Entries expire.
+	s.Lookups = NewLookupCache(time.Millisecond)
+	s.Open("missing")
+	time.Sleep(5 * time.Millisecond)
+	opens()
+	s.Open("missing")
+	if a, b := opens(); a != 1 || b != 1 {
+		t.Errorf("opens after expiry = %d, %d, want 1, 1", a, b)
+	}
+}
+
+func TestLookupCacheWatched(t *T) {
+	top := &countBundle{contents: map[string]string{}}
+	bottom := zipOf(t, "x", "bottom")
+	w := &funcWatcher{}
+	lc := NewLookupCache(0)
+	sp := NewSearchPath(Watched(top, w), bottom)
+	sp.Configure(func(s *Sequence) { s.Lookups = lc })
+
+	// The watched layer invalidates the cache by itself.
+	if _, err := sp.Open("override"); err != ErrNotFound {
+		t.Fatalf("Open(override) = %v, want ErrNotFound", err)
+	}
+	top.contents["override"] = "top"
+	w.fn("override")
+	if data := readAll(t, sp, "override"); data != "top" {
+		t.Errorf("Open(override) = %q, want top", data)
+	}
+
+	// Changing the search path invalidates the cache.
+	sp.Open("missing")
+	sp.Prepend(zipOf(t, "missing", "now here"))
+	if data := readAll(t, sp, "missing"); data != "now here" {
+		t.Errorf("Open(missing) after Prepend = %q", data)
+	}
+
+	lc.Close()
+	if w.fn != nil {
+		t.Error("Close didn't stop watching")
+	}
+
+	// Where a path is found isn't remembered with a Strategy.
+	s := &Sequence{Layers: BundleSequence{top, bottom}, Strategy: LastWins, Lookups: NewLookupCache(0)}
+	readAll(t, s, "x")
+	top.contents["x"] = "top"
+	top.opens = 0
+	readAll(t, s, "x")
+	if top.opens != 1 {
+		t.Errorf("top opened %d times with a Strategy, want 1", top.opens)
+	}
+}
+
+func TestLookupCacheLayers(t *T) {
+	first := zipOf(t, "x", "first")
+	last := zipOf(t, "x", "last")
+	sp := NewSearchPath(first, last)
+	sp.Configure(func(s *Sequence) { s.Lookups = NewLookupCache(0) })
+	if data := readAll(t, sp, "x"); data != "first" {
+		t.Fatalf("Open(x) = %q, want first", data)
+	}
+
+	// Changing the Strategy forgets where x was found.
+	sp.Configure(func(s *Sequence) { s.Strategy = LastWins })
+	if data := readAll(t, sp, "x"); data != "last" {
+		t.Errorf("Open(x) with LastWins = %q, want last", data)
+	}
+
+	// A search of the old layers finishing after a change to the
+	// search path doesn't leave an entry for them.
+	sp.Configure(func(s *Sequence) { s.Strategy = nil })
+	sp.Set(zipOf(t, "y", "y"), last)
+	old := sp.sequence()
+	sp.Set(first, last)
+	if data := readAll(t, old, "x"); data != "last" {
+		t.Fatalf("Open(x) in the old layers = %q, want last", data)
+	}
+	if data := readAll(t, sp, "x"); data != "first" {
+		t.Errorf("Open(x) after Set = %q, want first", data)
+	}
+
+	// So does assigning other Layers to a Sequence.
+	s := &Sequence{Layers: BundleSequence{first, last}, Lookups: NewLookupCache(0)}
+	readAll(t, s, "x")
+	s.Layers = BundleSequence{last, first}
+	if data := readAll(t, s, "x"); data != "last" {
+		t.Errorf("Open(x) after reordering = %q, want last", data)
+	}
+}
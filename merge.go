@@ -0,0 +1,195 @@
+package resources
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// A FindAller finds a resource in each of several layers, such as the
+// sub-bundles of a BundleSequence.
+type FindAller interface {
+	// FindAll returns the resource at path from every layer which
+	// has one, highest priority first, or ErrNotFound if none do.
+	FindAll(path string) ([]Resource, error)
+}
+
+// An ArrayMode is how MergeJSON merges arrays.
+type ArrayMode int
+
+const (
+	// ArrayReplace replaces arrays of lower priority layers.
+	ArrayReplace ArrayMode = iota
+
+	// ArrayAppend appends the elements of arrays of higher priority
+	// layers to those of lower priority layers.
+	ArrayAppend
+)
+
+// MergeOptions control how MergeJSON merges documents.
+type MergeOptions struct {
+	Arrays ArrayMode
+}
+
+// MergedJSON is the result of merging a JSON document from several
+// layers.
+type MergedJSON struct {
+	// Value is the merged document, as decoded by encoding/json
+	// into an interface{} value, with numbers as json.Number.
+	Value any
+
+	// Sources maps the JSON Pointer (RFC 6901) of each value which
+	// isn't an object, eg: "/server/port", to the resource which set
+	// it. With ArrayAppend, each array element is also mapped, eg:
+	// "/plugins/2".
+	Sources map[string]Resource
+}
+
+// Source returns the resource which set the value at the JSON Pointer,
+// or nil if it is an object, or isn't set.
+func (m *MergedJSON) Source(pointer string) Resource {
+	return m.Sources[pointer]
+}
+
+// Decode stores the merged document in the value pointed to by v, as
+// json.Unmarshal does.
+func (m *MergedJSON) Decode(v any) error {
+	data, err := json.Marshal(m.Value)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// pointerEscaper escapes the keys of objects in JSON Pointers.
+var pointerEscaper = strings.NewReplacer("~", "~0", "/", "~1")
+
+// merger merges one layer after another into a document.
+type merger struct {
+	opts    *MergeOptions
+	sources map[string]Resource
+}
+
+// record maps the pointers of v and, if it is an object, its values, to
+// src.
+func (mg *merger) record(pointer string, v any, src Resource) {
+	for p := range mg.sources {
+		if strings.HasPrefix(p, pointer+"/") {
+			delete(mg.sources, p)
+		}
+	}
+	switch v := v.(type) {
+	case map[string]any:
+		delete(mg.sources, pointer)
+		for key, elem := range v {
+			mg.record(pointer+"/"+pointerEscaper.Replace(key), elem, src)
+		}
+	case []any:
+		mg.sources[pointer] = src
+		if mg.opts.Arrays == ArrayAppend {
+			for i := range v {
+				mg.sources[pointer+"/"+strconv.Itoa(i)] = src
+			}
+		}
+	default:
+		mg.sources[pointer] = src
+	}
+}
+
+// merge merges over, from src, on top of base.
+func (mg *merger) merge(pointer string, base, over any, src Resource) any {
+	switch over := over.(type) {
+	case map[string]any:
+		if base, ok := base.(map[string]any); ok {
+			for key, v := range over {
+				base[key] = mg.merge(pointer+"/"+pointerEscaper.Replace(key), base[key], v, src)
+			}
+			return base
+		}
+	case []any:
+		if base, ok := base.([]any); ok && mg.opts.Arrays == ArrayAppend {
+			mg.sources[pointer] = src
+			for i := range over {
+				mg.sources[pointer+"/"+strconv.Itoa(len(base)+i)] = src
+			}
+			return append(base, over...)
+		}
+	}
+	mg.record(pointer, over, src)
+	return over
+}
+
+func decodeJSON(rsrc Resource) (any, error) {
+	rdr, err := rsrc.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rdr.Close()
+	dec := json.NewDecoder(rdr)
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, fmt.Errorf("resources: decoding %v: %w", rsrc, err)
+	}
+	return v, nil
+}
+
+// MergeJSON merges the JSON documents at path in every layer of s,
+// which is usually a BundleSequence, OwningSequence or SearchPath.
+// Objects are merged key by key, with the values of higher priority
+// layers replacing those of lower ones, and arrays merged as set by
+// opts, which may be nil for the defaults. If s doesn't implement
+// FindAller, the document is found with Find.
+//
+// For example, with defaults in an embedded zip file, overridden by
+// a file beside the executable, and one in the working directory:
+//
+//	seq := resources.BundleSequence{cwd, exe_dir, embedded}
+//	merged, err := resources.MergeJSON(seq, "config.json", nil)
+func MergeJSON(s Searcher, path string, opts *MergeOptions) (*MergedJSON, error) {
+	if opts == nil {
+		opts = new(MergeOptions)
+	}
+	var layers []Resource
+	if fa, ok := s.(FindAller); ok {
+		var err error
+		if layers, err = fa.FindAll(path); err != nil {
+			return nil, err
+		}
+	} else {
+		rsrc, err := s.Find(path)
+		if err != nil {
+			return nil, err
+		}
+		layers = []Resource{rsrc}
+	}
+
+	mg := &merger{opts: opts, sources: make(map[string]Resource)}
+	var merged any
+	for i := len(layers) - 1; i >= 0; i-- {
+		v, err := decodeJSON(layers[i])
+		if err != nil {
+			return nil, err
+		}
+		if i == len(layers)-1 {
+			mg.record("", v, layers[i])
+			merged = v
+		} else {
+			merged = mg.merge("", merged, v, layers[i])
+		}
+	}
+	return &MergedJSON{merged, mg.sources}, nil
+}
+
+// LoadMerged merges the JSON documents at path in every layer of s, as
+// MergeJSON does, and decodes the result as a T.
+func LoadMerged[T any](s Searcher, path string, opts *MergeOptions) (T, error) {
+	var v T
+	merged, err := MergeJSON(s, path, opts)
+	if err != nil {
+		return v, err
+	}
+	err = merged.Decode(&v)
+	return v, err
+}
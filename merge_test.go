@@ -0,0 +1,97 @@
+package resources
+
+import (
+	"reflect"
+	. "testing"
+)
+
+var BundleSequence_Is_A_FindAller FindAller = BundleSequence{}
+var OwningSequence_Is_A_FindAller FindAller = OwningSequence{}
+var SearchPath_Is_A_FindAller FindAller = &SearchPath{}
+
+func TestFindAll(t *T) {
+	a, b := zipOf(t, "x", "a"), zipOf(t, "y", "b")
+	c := zipOf(t, "x", "c")
+	found, err := BundleSequence{a, nil, b, &closeBundle{}, c}.FindAll("x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 2 {
+		t.Fatalf("FindAll(x) = %v, want 2 resources", found)
+	}
+	for i, want := range []string{"a", "c"} {
+		rdr, _ := found[i].Open()
+		data := make([]byte, 1)
+		rdr.Read(data)
+		rdr.Close()
+		if string(data) != want {
+			t.Errorf("FindAll(x)[%d] = %q, want %q", i, data, want)
+		}
+	}
+	if _, err := (BundleSequence{a, b}).FindAll("z"); err != ErrNotFound {
+		t.Errorf("FindAll(z) = %v, want ErrNotFound", err)
+	}
+}
+
+func TestMergeJSON(t *T) {
+	defaults := zipOf(t, "config.json", `{
+		"server": {"host": "localhost", "port": 80, "tls": {"cert": "a.pem"}},
+		"plugins": ["core"],
+		"debug": false
+	}`)
+	exe_dir := zipOf(t, "config.json", `{"server": {"port": 8080}, "plugins": ["extra"]}`)
+	cwd := zipOf(t, "config.json", `{"debug": true, "server": {"tls": null}, "a/b": 1}`)
+	seq := BundleSequence{cwd, exe_dir, defaults}
+
+	type config struct {
+		Server struct {
+			Host string
+			Port int
+			TLS  *struct{ Cert string }
+		}
+		Plugins []string
+		Debug   bool
+	}
+
+	merged, err := MergeJSON(seq, "config.json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Log(merged.Value)
+	var cfg config
+	if err := merged.Decode(&cfg); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Server.Host != "localhost" || cfg.Server.Port != 8080 || cfg.Server.TLS != nil || !cfg.Debug {
+		t.Errorf("merged config = %+v", cfg)
+	}
+	if !reflect.DeepEqual(cfg.Plugins, []string{"extra"}) {
+		t.Errorf("Plugins = %v, want [extra]", cfg.Plugins)
+	}
+
+	layers := map[string]Bundle{"defaults": defaults, "exe_dir": exe_dir, "cwd": cwd}
+	for pointer, want := range map[string]string{
+		"/server/host": "defaults",
+		"/server/port": "exe_dir",
+		"/server/tls":  "cwd",
+		"/debug":       "cwd",
+		"/plugins":     "exe_dir",
+		"/a~1b":        "cwd",
+	} {
+		src, ok := merged.Source(pointer).(*zipResource)
+		if !ok || src.zb != layers[want] {
+			t.Errorf("Source(%s) is not from %s", pointer, want)
+		}
+	}
+	if merged.Source("/server/tls/cert") != nil {
+		t.Error("Source(/server/tls/cert) set after tls was replaced")
+	}
+
+	cfg, err = LoadMerged[config](seq, "config.json", &MergeOptions{Arrays: ArrayAppend})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(cfg.Plugins, []string{"core", "extra"}) {
+		t.Errorf("appended Plugins = %v, want [core extra]", cfg.Plugins)
+	}
+}
@@ -0,0 +1,117 @@
+/*
+Package metrics counts the operations on resource bundles, and
+publishes the counts with expvar.
+
+It is separate from package resources as importing expvar registers
+the /debug/vars handler on http.DefaultServeMux.
+*/
+package metrics
+
+import (
+	"errors"
+	"expvar"
+	"fmt"
+	"sync"
+	"time"
+
+	"gopkg.in/cookieo9/resources-go.v2"
+)
+
+// opMetrics are the expvar counters of one Op.
+type opMetrics struct {
+	calls, errors, misses, nanos *expvar.Int
+}
+
+type observer struct {
+	ops          map[resources.Op]*opMetrics
+	reads, bytes *expvar.Int
+}
+
+func (o *observer) Begin(op resources.Op, path string) func(error) {
+	start := time.Now()
+	m := o.ops[op]
+	return func(err error) {
+		m.calls.Add(1)
+		m.nanos.Add(int64(time.Since(start)))
+		if errors.Is(err, resources.ErrNotFound) {
+			m.misses.Add(1)
+		} else if err != nil {
+			m.errors.Add(1)
+		}
+	}
+}
+
+func (o *observer) Read(path string, n int64, err error) {
+	o.reads.Add(1)
+	o.bytes.Add(n)
+}
+
+// counter returns the counter in vars with the given key, adding it if
+// it doesn't exist.
+func counter(vars *expvar.Map, key string) (*expvar.Int, error) {
+	switch v := vars.Get(key).(type) {
+	case nil:
+		n := new(expvar.Int)
+		vars.Set(key, n)
+		return n, nil
+	case *expvar.Int:
+		return v, nil
+	}
+	return nil, fmt.Errorf("metrics: %s isn't an expvar.Int", key)
+}
+
+// mu serialises the creation of the expvar maps.
+var mu sync.Mutex
+
+// New returns a Middleware which counts the operations on the bundles
+// it wraps, and publishes the counts with expvar as a map with the
+// given name, containing for each Op (eg: "open"):
+//
+//	"open.calls"   number of calls
+//	"open.errors"  number of calls which failed, other than misses
+//	"open.misses"  number of calls which failed with ErrNotFound
+//	"open.nanos"   total time spent in the calls
+//
+// and "read.count" and "read.bytes", the number of readers closed, and
+// the bytes read from them. Middlewares with the same name share the
+// same map, and so their counts. It fails if name is already published
+// as something other than an expvar.Map.
+func New(name string) (resources.Middleware, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	var vars *expvar.Map
+	switch v := expvar.Get(name).(type) {
+	case nil:
+		vars = expvar.NewMap(name)
+	case *expvar.Map:
+		vars = v
+	default:
+		return nil, fmt.Errorf("metrics: %s is already published as a %T", name, v)
+	}
+
+	var err error
+	get := func(key string) *expvar.Int {
+		n, cerr := counter(vars, key)
+		if cerr != nil && err == nil {
+			err = cerr
+		}
+		return n
+	}
+	o := &observer{
+		ops:   make(map[resources.Op]*opMetrics),
+		reads: get("read.count"),
+		bytes: get("read.bytes"),
+	}
+	for _, op := range []resources.Op{resources.OpOpen, resources.OpFind, resources.OpGlob, resources.OpList} {
+		o.ops[op] = &opMetrics{
+			calls:  get(string(op) + ".calls"),
+			errors: get(string(op) + ".errors"),
+			misses: get(string(op) + ".misses"),
+			nanos:  get(string(op) + ".nanos"),
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return resources.Instrument(o), nil
+}
@@ -0,0 +1,82 @@
+package metrics
+
+import (
+	"expvar"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	. "testing"
+
+	"gopkg.in/cookieo9/resources-go.v2"
+)
+
+// runs numbers the names published by the tests, which can't be
+// unpublished, so that they can run more than once, eg: with -count.
+var runs int32
+
+func uniqueName(prefix string) string {
+	return fmt.Sprintf("%s-%d", prefix, atomic.AddInt32(&runs, 1))
+}
+
+func TestMetrics(t *T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "foo.txt"), []byte("foo is foo"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	name := uniqueName("test-metrics")
+	mw, err := New(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := mw(resources.OpenDir(dir))
+
+	rdr, err := b.Open("foo.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ioutil.ReadAll(rdr)
+	rdr.Close()
+	rdr.Close() // counted once
+	if _, err := b.Open("missing"); err != resources.ErrNotFound {
+		t.Errorf("Open(missing) = %v, want ErrNotFound", err)
+	}
+	if _, err := b.(resources.Searcher).Find("foo.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	vars := expvar.Get(name).(*expvar.Map)
+	for key, want := range map[string]int64{
+		"open.calls":  2,
+		"open.misses": 1,
+		"open.errors": 0,
+		"find.calls":  1,
+		"read.count":  1,
+		"read.bytes":  10,
+	} {
+		if got := vars.Get(key).(*expvar.Int).Value(); got != want {
+			t.Errorf("%s = %d, want %d", key, got, want)
+		}
+	}
+
+	// A second middleware of the same name shares the counts.
+	mw, err = New(name)
+	if err != nil {
+		t.Fatalf("second New(%s): %v", name, err)
+	}
+	mw(resources.OpenDir(dir)).Open("missing")
+	if got := vars.Get("open.misses").(*expvar.Int).Value(); got != 2 {
+		t.Errorf("open.misses = %d, want 2", got)
+	}
+}
+
+func TestMetricsNameTaken(t *T) {
+	name := uniqueName("test-metrics-taken")
+	if expvar.Get(name) == nil {
+		expvar.NewString(name)
+	}
+	if _, err := New(name); err == nil {
+		t.Error("New() of a published string succeeded")
+	}
+}
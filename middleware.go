@@ -0,0 +1,261 @@
+package resources
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"runtime/trace"
+	"time"
+)
+
+// A Middleware wraps a Bundle to change or observe how it is used.
+type Middleware func(Bundle) Bundle
+
+// Chain wraps b with each of the middlewares, the first of them being
+// the outermost, so it sees each call first.
+func Chain(b Bundle, middlewares ...Middleware) Bundle {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		b = middlewares[i](b)
+	}
+	return b
+}
+
+// An Op is an operation on a bundle, as seen by an Observer.
+type Op string
+
+const (
+	OpOpen Op = "open" // Bundle.Open, and Resource.Open of found resources
+	OpFind Op = "find"
+	OpGlob Op = "glob"
+	OpList Op = "list"
+)
+
+// An Observer is told about the operations on a bundle wrapped with
+// Instrument. Its methods may be called concurrently.
+type Observer interface {
+	// Begin is called when an operation starts, with the path or
+	// pattern it was given ("" for List), and the returned function
+	// is called when it returns, with its error.
+	Begin(op Op, path string) (end func(err error))
+
+	// Read is called when a reader of the resource at path is
+	// closed, with the number of bytes read from it, and the first
+	// error other than io.EOF returned by its Read.
+	Read(path string, n int64, err error)
+}
+
+// Instrument returns a Middleware which tells obs about the operations
+// on the bundles it wraps. The wrapped bundles implement Searcher and
+// Lister only if the bundles they wrap do.
+func Instrument(obs Observer) Middleware {
+	return func(b Bundle) Bundle {
+		ib := &instrumentedBundle{b, obs}
+		_, searcher := b.(Searcher)
+		_, lister := b.(Lister)
+		switch {
+		case searcher && lister:
+			return &instrumentedSearchLister{ib}
+		case searcher:
+			return &instrumentedSearcher{ib}
+		case lister:
+			return &instrumentedLister{ib}
+		}
+		return ib
+	}
+}
+
+type instrumentedBundle struct {
+	bundle Bundle
+	obs    Observer
+}
+
+type instrumentedSearcher struct{ *instrumentedBundle }
+type instrumentedLister struct{ *instrumentedBundle }
+type instrumentedSearchLister struct{ *instrumentedBundle }
+
+// Bundles instruments each layer of the wrapped bundle.
+func (ib *instrumentedBundle) Bundles() []Bundle {
+	var layers []Bundle
+	for _, layer := range layersOf(ib.bundle) {
+		if layer != nil {
+			layers = append(layers, Instrument(ib.obs)(layer))
+		}
+	}
+	return layers
+}
+
+func (ib *instrumentedBundle) wrapped() Bundle {
+	return ib.bundle
+}
+
+func (ib *instrumentedBundle) Close() error {
+	return ib.bundle.Close()
+}
+
+func (ib *instrumentedBundle) open(path string, open_fn func() (io.ReadCloser, error)) (io.ReadCloser, error) {
+	end := ib.obs.Begin(OpOpen, path)
+	rdr, err := open_fn()
+	end(err)
+	if err != nil {
+		return nil, err
+	}
+	return &countingReader{ReadCloser: rdr, path: path, obs: ib.obs}, nil
+}
+
+func (ib *instrumentedBundle) Open(path string) (io.ReadCloser, error) {
+	return ib.open(path, func() (io.ReadCloser, error) {
+		return ib.bundle.Open(path)
+	})
+}
+
+func (ib *instrumentedBundle) find(path string) (Resource, error) {
+	end := ib.obs.Begin(OpFind, path)
+	rsrc, err := ib.bundle.(Searcher).Find(path)
+	end(err)
+	if err != nil {
+		return nil, err
+	}
+	return &instrumentedResource{rsrc, ib}, nil
+}
+
+func (ib *instrumentedBundle) glob(pattern string) ([]Resource, error) {
+	end := ib.obs.Begin(OpGlob, pattern)
+	rsrcs, err := ib.bundle.(Searcher).Glob(pattern)
+	end(err)
+	return ib.wrap(rsrcs), err
+}
+
+func (ib *instrumentedBundle) list() ([]Resource, error) {
+	end := ib.obs.Begin(OpList, "")
+	rsrcs, err := ib.bundle.(Lister).List()
+	end(err)
+	return ib.wrap(rsrcs), err
+}
+
+func (ib *instrumentedBundle) wrap(rsrcs []Resource) []Resource {
+	wrapped := make([]Resource, len(rsrcs))
+	for i, rsrc := range rsrcs {
+		wrapped[i] = &instrumentedResource{rsrc, ib}
+	}
+	return wrapped
+}
+
+func (is *instrumentedSearcher) Find(path string) (Resource, error) {
+	return is.find(path)
+}
+
+func (is *instrumentedSearcher) Glob(pattern string) ([]Resource, error) {
+	return is.glob(pattern)
+}
+
+func (il *instrumentedLister) List() ([]Resource, error) {
+	return il.list()
+}
+
+func (isl *instrumentedSearchLister) Find(path string) (Resource, error) {
+	return isl.find(path)
+}
+
+func (isl *instrumentedSearchLister) Glob(pattern string) ([]Resource, error) {
+	return isl.glob(pattern)
+}
+
+func (isl *instrumentedSearchLister) List() ([]Resource, error) {
+	return isl.list()
+}
+
+// instrumentedResource is a resource whose Open is observed.
+type instrumentedResource struct {
+	Resource
+	ib *instrumentedBundle
+}
+
+func (ir *instrumentedResource) Open() (io.ReadCloser, error) {
+	return ir.ib.open(ir.Path(), ir.Resource.Open)
+}
+
+// countingReader counts the bytes read from a resource, for its
+// Observer, which is told once, when it is first closed.
+type countingReader struct {
+	io.ReadCloser
+	path   string
+	obs    Observer
+	n      int64
+	err    error
+	closed bool
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.ReadCloser.Read(p)
+	cr.n += int64(n)
+	if err != nil && err != io.EOF && cr.err == nil {
+		cr.err = err
+	}
+	return n, err
+}
+
+func (cr *countingReader) Close() error {
+	err := cr.ReadCloser.Close()
+	if !cr.closed {
+		cr.closed = true
+		cr.obs.Read(cr.path, cr.n, cr.err)
+	}
+	return err
+}
+
+type logObserver struct {
+	logger *slog.Logger
+}
+
+func (lo *logObserver) Begin(op Op, path string) func(error) {
+	start := time.Now()
+	return func(err error) {
+		if errors.Is(err, ErrNotFound) {
+			lo.logger.Info("resource not found", "op", string(op), "path", path)
+		} else if err != nil {
+			lo.logger.Error("resource error", "op", string(op), "path", path,
+				"duration", time.Since(start), "error", err)
+		}
+	}
+}
+
+func (lo *logObserver) Read(path string, n int64, err error) {
+	if err != nil {
+		lo.logger.Error("resource read error", "path", path, "bytes", n, "error", err)
+	}
+}
+
+// Logging returns a Middleware which logs the misses (at Info level)
+// and errors (at Error level) of the operations on the bundles it
+// wraps. If logger is nil, slog.Default() is used.
+func Logging(logger *slog.Logger) Middleware {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return Instrument(&logObserver{logger})
+}
+
+type traceObserver struct{}
+
+func (traceObserver) Begin(op Op, path string) func(error) {
+	ctx := context.Background()
+	region := trace.StartRegion(ctx, "resources."+string(op))
+	trace.Log(ctx, "path", path)
+	return func(err error) {
+		if err != nil {
+			trace.Log(ctx, "error", err.Error())
+		}
+		region.End()
+	}
+}
+
+func (traceObserver) Read(path string, n int64, err error) {}
+
+// Tracing returns a Middleware which wraps the operations on the
+// bundles it wraps in runtime/trace regions, named "resources.open",
+// "resources.find", etc. Reading resources isn't traced, as it may
+// happen on other goroutines than opening them.
+func Tracing() Middleware {
+	return Instrument(traceObserver{})
+}
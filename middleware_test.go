@@ -0,0 +1,70 @@
+package resources
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	. "testing"
+)
+
+func TestInstrumentCapabilities(t *T) {
+	zr := CreateTestZip(t)
+	zb, err := OpenZipReader(zr, int64(zr.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, b := range []Bundle{zb, &closeBundle{}} {
+		wrapped := Chain(b, Logging(nil), Tracing())
+		_, searcher := b.(Searcher)
+		_, lister := b.(Lister)
+		_, wsearcher := wrapped.(Searcher)
+		_, wlister := wrapped.(Lister)
+		t.Logf("%T -> %T", b, wrapped)
+		if searcher != wsearcher || lister != wlister {
+			t.Errorf("%T: wrapped Searcher=%v Lister=%v, want %v %v", b, wsearcher, wlister, searcher, lister)
+		}
+	}
+}
+
+// countObserver counts the calls to Read.
+type countObserver struct{ reads int }
+
+func (co *countObserver) Begin(op Op, path string) func(error) { return func(error) {} }
+func (co *countObserver) Read(path string, n int64, err error) { co.reads++ }
+
+func TestInstrumentCloseTwice(t *T) {
+	zr := CreateTestZip(t)
+	zb, err := OpenZipReader(zr, int64(zr.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	obs := &countObserver{}
+	rdr, err := Instrument(obs)(zb).Open("foo.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rdr.Close()
+	rdr.Close()
+	if obs.reads != 1 {
+		t.Errorf("Read called %d times, want 1", obs.reads)
+	}
+}
+
+func TestLogging(t *T) {
+	zr := CreateTestZip(t)
+	zb, err := OpenZipReader(zr, int64(zr.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := new(bytes.Buffer)
+	b := Logging(slog.New(slog.NewTextHandler(buf, nil)))(zb)
+	readAll(t, b, "foo.txt")
+	if buf.Len() != 0 {
+		t.Errorf("logged a successful open: %s", buf)
+	}
+	b.Open("missing")
+	t.Log(buf)
+	if !strings.Contains(buf.String(), "resource not found") || !strings.Contains(buf.String(), "path=missing") {
+		t.Errorf("miss not logged: %s", buf)
+	}
+}
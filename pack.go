@@ -0,0 +1,151 @@
+package resources
+
+import (
+	"archive/zip"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	"gopkg.in/cookieo9/resources-go.v2/internal/match"
+)
+
+// PackOptions control which resources Pack writes into a zip file,
+// and how.
+type PackOptions struct {
+	// Include and Exclude are path.Match patterns selecting
+	// resources. Patterns without a slash match the base name of a
+	// resource, others match its whole path. If Include is empty all
+	// resources are included, then any matching Exclude are skipped.
+	Include []string
+	Exclude []string
+
+	// Filter, if not nil, is called with the path of each resource
+	// selected by the patterns, and only those it returns true for
+	// are written.
+	Filter func(path string) bool
+
+	// Deterministic sets all modification times to the start of
+	// 1980 (the earliest time a zip file can store) and normalizes
+	// file modes, so the same resources always produce the same zip file,
+	// eg: for reproducible builds.
+	Deterministic bool
+
+	// Key, if set, encrypts each resource with NewEncryptWriter, to
+	// be read with OpenEncrypted. Encrypted resources are stored
+	// without compression.
+	Key []byte
+}
+
+// zipEpoch is the earliest time which can be stored in a zip file.
+var zipEpoch = time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// selected reports whether the resource path p is selected by
+// the Include and Exclude patterns, and the Filter.
+func (opts *PackOptions) selected(p string) (bool, error) {
+	if len(opts.Include) > 0 {
+		if match, err := match.Any(opts.Include, p); err != nil || !match {
+			return false, err
+		}
+	}
+	if match, err := match.Any(opts.Exclude, p); err != nil || match {
+		return false, err
+	}
+	return opts.Filter == nil || opts.Filter(p), nil
+}
+
+// Pack writes the resources listed by src into zw, in path order,
+// skipping directories. The resources are compressed with the
+// Deflate method, unless encrypted. If opts is nil, all resources are written and keep
+// their modification times.
+func Pack(zw *zip.Writer, src Lister, opts *PackOptions) error {
+	if opts == nil {
+		opts = new(PackOptions)
+	}
+
+	list, err := src.List()
+	if err != nil {
+		return err
+	}
+	list = append([]Resource(nil), list...)
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Path() < list[j].Path()
+	})
+
+	for _, rsrc := range list {
+		if ok, err := opts.selected(rsrc.Path()); err != nil {
+			return err
+		} else if !ok {
+			continue
+		}
+		if err := opts.writeEntry(zw, rsrc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteZip writes every resource listed by src into zw, as by
+// Pack with no options.
+func WriteZip(zw *zip.Writer, src Lister) error {
+	return Pack(zw, src, nil)
+}
+
+func (opts *PackOptions) writeEntry(zw *zip.Writer, rsrc Resource) error {
+	info, err := rsrc.Stat()
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return nil
+	}
+	hdr, err := zip.FileInfoHeader(info)
+	if err != nil {
+		return err
+	}
+	hdr.Name = rsrc.Path()
+	hdr.Method = zip.Deflate
+	if opts.Deterministic {
+		hdr.Modified = zipEpoch
+		hdr.SetMode(0644)
+	}
+	if opts.Key != nil {
+		hdr.Method = zip.Store
+	}
+
+	fw, err := zw.CreateHeader(hdr)
+	if err != nil {
+		return err
+	}
+	rdr, err := rsrc.Open()
+	if err != nil {
+		return err
+	}
+	defer rdr.Close()
+
+	if opts.Key == nil {
+		_, err = io.Copy(fw, rdr)
+		return err
+	}
+	ew, err := NewEncryptWriter(fw, opts.Key, rsrc.Path())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(ew, rdr); err != nil {
+		return err
+	}
+	return ew.Close()
+}
+
+// OpenDirOrZip opens path with OpenDir if it is a directory, and with
+// OpenZip otherwise. Either way, the returned Bundle implements Lister.
+func OpenDirOrZip(path string) (Bundle, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return OpenDir(path), nil
+	}
+	return OpenZip(path)
+}
@@ -0,0 +1,58 @@
+package resources
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	. "testing"
+)
+
+func packTestZip(t *T, opts *PackOptions) []byte {
+	zip_rdr := CreateTestZip(t)
+	src, err := OpenZipReader(zip_rdr, int64(zip_rdr.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buf := new(bytes.Buffer)
+	zw := zip.NewWriter(buf)
+	if err := Pack(zw, src.(Lister), opts); err != nil {
+		t.Fatal("Pack():", err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestPack(t *T) {
+	opts := &PackOptions{
+		Include:       []string{"*.txt", "MANIFEST"},
+		Exclude:       []string{"subfolder/*"},
+		Deterministic: true,
+	}
+	data := packTestZip(t, opts)
+	zb, err := OpenZipData(string(data))
+	if err != nil {
+		t.Fatal("OpenZipData():", err)
+	}
+
+	list, err := zb.(Lister).List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	for _, rsrc := range list {
+		paths = append(paths, rsrc.Path())
+		if info, _ := rsrc.Stat(); !info.ModTime().Equal(zipEpoch) {
+			t.Errorf("%s: ModTime() = %v, want %v", rsrc.Path(), info.ModTime(), zipEpoch)
+		}
+	}
+	if want := "[MANIFEST foo.txt]"; fmt.Sprint(paths) != want {
+		t.Errorf("Pack() wrote %v, want %s", paths, want)
+	}
+
+	if again := packTestZip(t, opts); !bytes.Equal(data, again) {
+		t.Error("Deterministic Pack() output differs between runs")
+	}
+}
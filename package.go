@@ -1,10 +1,12 @@
 package resources
 
 import (
+	"errors"
 	"go/build"
-	"os"
 	"path/filepath"
 	"runtime"
+	"runtime/debug"
+	"strings"
 )
 
 // Opens the source directory of the current package as a Bundle.
@@ -13,44 +15,48 @@ import (
 func OpenCurrentPackage() (Bundle, error) {
 	_, sfile, _, _ := runtime.Caller(1)
 	if p, err := build.ImportDir(filepath.Dir(sfile), build.FindOnly); err == nil {
-		return &packageBundle{OpenFS(p.Dir).(*fsBundle)}, nil
+		return openPackageDir(p.Dir)
 	} else {
 		return nil, err
 	}
 	panic("Shouldn't Get Here!")
 }
 
+// OpenMainPackage opens the source directory of the main package of
+// the running program as a Bundle, found using the import path
+// recorded in the executable's build information. For test binaries,
+// the package under test is used.
+//
+// The source directory is only found if the package can be located
+// by go/build, which is usually only true on the machine it was
+// built on.
+func OpenMainPackage() (Bundle, error) {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return nil, errors.New("resources: executable has no build information")
+	}
+	return OpenPackage(strings.TrimSuffix(info.Path, ".test"))
+}
+
 // OpenPackagePath returns a Bundle which accesses files
 // in the source directory of the package named by the given
 // import path.
 //
 // Bundles accessing packages support the Searcher and Lister
-// interfaces.
+// interfaces. They hide Go source files, version control directories,
+// editor files, and any files matched by the package's IgnoreFile (see
+// OpenIgnoring and DefaultIgnore). Other dot files, such as
+// .well-known, are kept.
 func OpenPackage(import_path string) (Bundle, error) {
 	pkg, err := build.Import(import_path, "", build.FindOnly)
 	if err != nil {
 		return nil, err
 	}
-	return &packageBundle{OpenFS(pkg.Dir).(*fsBundle)}, nil
-}
-
-type packageBundle struct {
-	*fsBundle
+	return openPackageDir(pkg.Dir)
 }
 
-func (pb *packageBundle) List() ([]Resource, error) {
-	var list []Resource
-	err := filepath.Walk(pb.base, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-		if !info.IsDir() {
-			rel, err := filepath.Rel(pb.base, path)
-			if err == nil {
-				list = append(list, pb.file(filepath.ToSlash(rel)))
-			}
-		}
-		return nil
-	})
-	return list, err
+// openPackageDir opens a package source directory, hiding the
+// DefaultIgnore files and those listed in its IgnoreFile.
+func openPackageDir(dir string) (Bundle, error) {
+	return OpenIgnoring(OpenDir(dir), DefaultIgnore...)
 }
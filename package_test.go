@@ -1,6 +1,7 @@
 package resources
 
 import (
+	"strings"
 	. "testing"
 )
 
@@ -16,6 +17,11 @@ func TestPackageBundle(t *T) {
 		t.Fatal("cp.List():", err)
 	}
 	t.Log("cp.List():", list)
+	for _, rsrc := range list {
+		if p := rsrc.Path(); strings.HasSuffix(p, ".go") || strings.HasPrefix(p, ".git/") {
+			t.Errorf("cp.List() includes ignored file %s", rsrc.Path())
+		}
+	}
 	if fs, err := cp.(Searcher).Glob("*.go"); err != nil {
 		t.Fatal("Glob(*.go):", err)
 	} else {
@@ -0,0 +1,104 @@
+package resources
+
+import (
+	"context"
+)
+
+// layerResult is the result of looking something up in one layer.
+type layerResult[R any] struct {
+	ctx     context.Context
+	cancel  context.CancelFunc
+	value   R
+	err     error
+	skipped bool          // the lookup wasn't started
+	done    chan struct{} // closed once value and err, or skipped, are set
+}
+
+// eachLayer looks something up with fn in each non-nil layer of s, and
+// passes the results to use in layer order, until it returns stop, or
+// an error, which eachLayer returns.
+//
+// If s.Parallel is more than 1, that many lookups run at once, and use
+// is called with each result as soon as those of the layers before it
+// have been used. Once use stops, no more lookups are started, the
+// contexts of those still running are cancelled, and their results are
+// passed to discard, if not nil, when they finish. The contexts of the
+// layers whose results were used aren't cancelled, so readers opened
+// with them keep working.
+func eachLayer[R any](s *Sequence, fn func(context.Context, Bundle) (R, error), use func(i int, b Bundle, v R, err error) (bool, error), discard func(R)) error {
+	if s.Parallel <= 1 {
+		for i, bundle := range s.Layers {
+			if bundle == nil {
+				continue
+			}
+			v, err := fn(context.Background(), bundle)
+			if stop, err := use(i, bundle, v, err); stop || err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	layers := s.Layers
+	results := make([]layerResult[R], len(layers))
+	for i := range results {
+		results[i].ctx, results[i].cancel = context.WithCancel(context.Background())
+		results[i].done = make(chan struct{})
+	}
+	stop := make(chan struct{})
+	go func() {
+		sem := make(chan struct{}, s.Parallel)
+		for i, bundle := range layers {
+			r := &results[i]
+			if bundle == nil {
+				r.skipped = true
+				close(r.done)
+				continue
+			}
+			select {
+			case sem <- struct{}{}:
+			case <-stop:
+			}
+			// Both may be ready, so check stop again before starting.
+			select {
+			case <-stop:
+				for j := i; j < len(results); j++ {
+					results[j].skipped = true
+					close(results[j].done)
+				}
+				return
+			default:
+			}
+			go func() {
+				r.value, r.err = fn(r.ctx, bundle)
+				<-sem
+				close(r.done)
+			}()
+		}
+	}()
+
+	for i, bundle := range layers {
+		r := &results[i]
+		<-r.done
+		if r.skipped {
+			continue
+		}
+		if stop_here, err := use(i, bundle, r.value, r.err); stop_here || err != nil {
+			close(stop)
+			for j := i + 1; j < len(results); j++ {
+				results[j].cancel()
+			}
+			go func(rest []layerResult[R]) {
+				for j := range rest {
+					<-rest[j].done
+					if !rest[j].skipped && rest[j].err == nil && discard != nil {
+						discard(rest[j].value)
+					}
+				}
+			}(results[i+1:])
+			return err
+		}
+	}
+	close(stop)
+	return nil
+}
@@ -0,0 +1,194 @@
+package resources
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync/atomic"
+	. "testing"
+	"time"
+)
+
+// slowBundle opens its files after a delay, counting the lookups
+// running at once, and the readers left open.
+type slowBundle struct {
+	delay   time.Duration
+	files   map[string]string
+	running *int32
+	most    *int32
+	open    *int32
+}
+
+type countedReader struct {
+	io.Reader
+	open *int32
+}
+
+func (cr *countedReader) Close() error {
+	atomic.AddInt32(cr.open, -1)
+	return nil
+}
+
+func (sb *slowBundle) Open(path string) (io.ReadCloser, error) {
+	n := atomic.AddInt32(sb.running, 1)
+	defer atomic.AddInt32(sb.running, -1)
+	for {
+		most := atomic.LoadInt32(sb.most)
+		if n <= most || atomic.CompareAndSwapInt32(sb.most, most, n) {
+			break
+		}
+	}
+	time.Sleep(sb.delay)
+	data, ok := sb.files[path]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	atomic.AddInt32(sb.open, 1)
+	return &countedReader{strings.NewReader(data), sb.open}, nil
+}
+
+func (sb *slowBundle) Close() error {
+	return nil
+}
+
+func TestSequenceParallel(t *T) {
+	var running, most, open int32
+	slow := func(delay time.Duration, files map[string]string) Bundle {
+		return &slowBundle{delay, files, &running, &most, &open}
+	}
+	s := &Sequence{
+		Layers: BundleSequence{
+			slow(50*time.Millisecond, map[string]string{"a": "first"}),
+			slow(50*time.Millisecond, nil),
+			slow(10*time.Millisecond, map[string]string{"a": "third", "b": "third"}),
+			slow(10*time.Millisecond, map[string]string{"b": "fourth"}),
+			slow(10*time.Millisecond, map[string]string{"b": "fifth"}),
+		},
+		Parallel: 3,
+	}
+
+	if data := readAll(t, s, "b"); data != "third" {
+		t.Errorf("Open(b) = %q, want third", data)
+	}
+	if data := readAll(t, s, "a"); data != "first" {
+		t.Errorf("Open(a) = %q, want first", data)
+	}
+	if most := atomic.LoadInt32(&most); most > 3 {
+		t.Errorf("%d layers searched at once, want at most 3", most)
+	}
+
+	// Readers opened by lower priority layers are closed.
+	deadline := time.Now().Add(time.Second)
+	for atomic.LoadInt32(&open) != 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if n := atomic.LoadInt32(&open); n != 0 {
+		t.Errorf("%d readers left open", n)
+	}
+
+	if _, err := s.Open("missing"); err != ErrNotFound {
+		t.Errorf("Open(missing) = %v, want ErrNotFound", err)
+	}
+}
+
+// barrierBundle's Open waits until n of them are opening at once,
+// failing with errNotParallel if that takes too long.
+type barrierBundle struct {
+	running *int32
+	n       int32
+	all     chan struct{}
+}
+
+var errNotParallel = errors.New("layers weren't searched in parallel")
+
+func (bb *barrierBundle) Open(path string) (io.ReadCloser, error) {
+	if atomic.AddInt32(bb.running, 1) == bb.n {
+		close(bb.all)
+	}
+	select {
+	case <-bb.all:
+		return io.NopCloser(strings.NewReader(path)), nil
+	case <-time.After(5 * time.Second):
+		return nil, errNotParallel
+	}
+}
+
+func (bb *barrierBundle) Close() error {
+	return nil
+}
+
+func TestSequenceParallelOverlap(t *T) {
+	var running int32
+	all := make(chan struct{})
+	s := &Sequence{Parallel: 3}
+	for i := 0; i < 3; i++ {
+		s.Layers = append(s.Layers, &barrierBundle{&running, 3, all})
+	}
+	if data := readAll(t, s, "x"); data != "x" {
+		t.Errorf("Open(x) = %q", data)
+	}
+}
+
+// ctxBundle is a ContextOpener which has its file once wait is closed,
+// with readers failing once their context is done, or which waits for
+// its context to be done if it has none, then closes cancelled.
+type ctxBundle struct {
+	file      string
+	wait      chan struct{}
+	cancelled chan struct{}
+}
+
+type ctxReader struct {
+	io.Reader
+	ctx context.Context
+}
+
+func (cr *ctxReader) Read(p []byte) (int, error) {
+	if err := cr.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return cr.Reader.Read(p)
+}
+
+func (cr *ctxReader) Close() error {
+	return nil
+}
+
+func (cb *ctxBundle) OpenContext(ctx context.Context, path string) (io.ReadCloser, error) {
+	if cb.file == "" {
+		close(cb.wait)
+		<-ctx.Done()
+		close(cb.cancelled)
+		return nil, ctx.Err()
+	}
+	<-cb.wait
+	return &ctxReader{strings.NewReader(cb.file), ctx}, nil
+}
+
+func (cb *ctxBundle) Open(path string) (io.ReadCloser, error) {
+	return cb.OpenContext(context.Background(), path)
+}
+
+func (cb *ctxBundle) Close() error {
+	return nil
+}
+
+func TestSequenceParallelCancel(t *T) {
+	started, cancelled := make(chan struct{}), make(chan struct{})
+	s := &Sequence{
+		Layers: BundleSequence{
+			&ctxBundle{file: "first", wait: started},
+			&ctxBundle{wait: started, cancelled: cancelled},
+		},
+		Parallel: 2,
+	}
+	if data := readAll(t, s, "x"); data != "first" {
+		t.Errorf("Open(x) = %q, want first", data)
+	}
+	select {
+	case <-cancelled:
+	case <-time.After(5 * time.Second):
+		t.Error("the lower priority Open wasn't cancelled")
+	}
+}
@@ -0,0 +1,69 @@
+package resources
+
+import (
+	"fmt"
+)
+
+// An ErrorPolicy is what a Sequence does when one of its layers fails
+// with an error other than ErrNotFound, eg: an unreachable HTTP server,
+// or a directory which can't be read. A BundleSequence always uses
+// FailFast; the policy of a SearchPath is set with Configure.
+type ErrorPolicy int
+
+const (
+	// FailFast returns the first error of a layer, unchanged, as a
+	// BundleSequence does.
+	FailFast ErrorPolicy = iota
+
+	// SkipErrors treats failing layers as if they didn't have the
+	// resources asked for. Their errors are only seen by OnError.
+	SkipErrors
+
+	// FailIfNoneSucceed skips failing layers, but fails if no layer
+	// succeeds: if a resource isn't found in any layer which didn't
+	// fail, or if every layer asked to Glob or List fails. The errors
+	// of all the failing layers are returned, as a MultiError if
+	// there are several.
+	FailIfNoneSucceed
+)
+
+// A LayerError is an error of one layer of a Sequence.
+type LayerError struct {
+	Layer  int // index of the layer in the Sequence
+	Bundle Bundle
+	Op     Op
+	Path   string // the path, or pattern, asked for
+	Err    error
+}
+
+func (e *LayerError) Error() string {
+	return fmt.Sprintf("resources: layer %d: %s %s: %v", e.Layer, e.Op, e.Path, e.Err)
+}
+
+func (e *LayerError) Unwrap() error {
+	return e.Err
+}
+
+// layerFailed reports the error of a layer to OnError, and returns it
+// if the Sequence must fail, or adds it to errs as a *LayerError
+// otherwise.
+func (s *Sequence) layerFailed(errs *[]error, layer int, b Bundle, op Op, path string, err error) error {
+	le := &LayerError{layer, b, op, path, err}
+	if s.OnError != nil {
+		s.OnError(le)
+	}
+	if s.Errors == FailFast {
+		return err
+	}
+	*errs = append(*errs, le)
+	return nil
+}
+
+// missing returns the error for a resource which no layer has, given
+// the errors of the layers which failed.
+func (s *Sequence) missing(errs []error) error {
+	if s.Errors == FailIfNoneSucceed && len(errs) > 0 {
+		return errorList(errs)
+	}
+	return ErrNotFound
+}
@@ -0,0 +1,93 @@
+package resources
+
+import (
+	"errors"
+	"io"
+	. "testing"
+)
+
+var errBroken = errors.New("broken")
+
+// brokenBundle fails everything with errBroken.
+type brokenBundle struct{}
+
+func (brokenBundle) Open(path string) (io.ReadCloser, error) { return nil, errBroken }
+func (brokenBundle) Find(path string) (Resource, error)      { return nil, errBroken }
+func (brokenBundle) Glob(pattern string) ([]Resource, error) { return nil, errBroken }
+func (brokenBundle) List() ([]Resource, error)               { return nil, errBroken }
+func (brokenBundle) Close() error                            { return nil }
+
+func TestSequenceErrorPolicies(t *T) {
+	good := zipOfFiles(t, map[string]string{"x": "good"})
+
+	s := &Sequence{Layers: BundleSequence{brokenBundle{}, good}}
+	var reported []*LayerError
+	s.OnError = func(le *LayerError) { reported = append(reported, le) }
+
+	// FailFast fails on the first layer, with its error.
+	if _, err := s.Open("x"); err != errBroken {
+		t.Errorf("FailFast Open: %v", err)
+	}
+	if len(reported) != 1 || reported[0].Layer != 0 || reported[0].Op != OpOpen {
+		t.Errorf("FailFast reported %v", reported)
+	}
+
+	// SkipErrors uses the second layer, and reports the first's error.
+	s.Errors = SkipErrors
+	reported = nil
+	if data := readAll(t, s, "x"); data != "good" {
+		t.Errorf("SkipErrors Open = %q", data)
+	}
+	if len(reported) != 1 || reported[0].Path != "x" {
+		t.Errorf("reported %v", reported)
+	}
+	if _, err := s.Find("missing"); err != ErrNotFound {
+		t.Errorf("SkipErrors Find(missing) = %v, want ErrNotFound", err)
+	}
+	if all, err := s.FindAll("x"); err != nil || len(all) != 1 {
+		t.Errorf("SkipErrors FindAll = %v, %v", all, err)
+	}
+
+	// FailIfNoneSucceed only fails when nothing is found.
+	s.Errors = FailIfNoneSucceed
+	if rsrc, err := s.Find("x"); err != nil || rsrc.Path() != "x" {
+		t.Errorf("FailIfNoneSucceed Find = %v, %v", rsrc, err)
+	}
+	if _, err := s.Find("missing"); !errors.Is(err, errBroken) {
+		t.Errorf("FailIfNoneSucceed Find(missing) = %v", err)
+	}
+	if list, err := s.List(); err != nil || len(list) != 1 {
+		t.Errorf("FailIfNoneSucceed List = %v, %v", list, err)
+	}
+
+	s.Layers = BundleSequence{brokenBundle{}, brokenBundle{}}
+	_, err := s.Glob("*")
+	var multi MultiError
+	if !errors.As(err, &multi) || len(multi) != 2 || !errors.Is(err, errBroken) {
+		t.Errorf("FailIfNoneSucceed Glob = %v", err)
+	}
+	t.Log(err)
+}
+
+func TestSearchPathErrorPolicy(t *T) {
+	good := zipOfFiles(t, map[string]string{"config.json": `{"name": "good"}`})
+	sp := NewSearchPath(brokenBundle{}, good)
+	if _, err := MergeJSON(sp, "config.json", nil); err != errBroken {
+		t.Errorf("FailFast MergeJSON = %v", err)
+	}
+
+	var reported []*LayerError
+	sp.Configure(func(s *Sequence) {
+		s.Errors = SkipErrors
+		s.OnError = func(le *LayerError) { reported = append(reported, le) }
+	})
+	if _, err := MergeJSON(sp, "config.json", nil); err != nil {
+		t.Errorf("SkipErrors MergeJSON = %v", err)
+	}
+	if data := readAll(t, sp, "config.json"); data != `{"name": "good"}` {
+		t.Errorf("SkipErrors Open = %q", data)
+	}
+	if len(reported) != 2 {
+		t.Errorf("reported %v, want the errors of FindAll and Open", reported)
+	}
+}
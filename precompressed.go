@@ -0,0 +1,300 @@
+package resources
+
+import (
+	"compress/gzip"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"sync"
+)
+
+// An Encoding describes a compressed form of resources, stored beside
+// the uncompressed resource with an extra extension, eg: "app.js.gz"
+// for "app.js".
+type Encoding struct {
+	// Name is the HTTP Content-Encoding of the compressed form.
+	Name string
+
+	// Ext is the extension added to the path of compressed
+	// resources, including the leading dot.
+	Ext string
+
+	// NewReader decompresses the data read from r.
+	NewReader func(r io.Reader) (io.ReadCloser, error)
+
+	// Size, if not nil, returns the uncompressed size of the
+	// compressed data in r without decompressing it.
+	Size func(r io.ReadSeeker) (int64, error)
+}
+
+var (
+	encodingsMu sync.RWMutex
+	encodings   = []Encoding{{
+		Name: "gzip",
+		Ext:  ".gz",
+		NewReader: func(r io.Reader) (io.ReadCloser, error) {
+			return gzip.NewReader(r)
+		},
+		Size: gzipSize,
+	}}
+)
+
+// gzipSize reads the uncompressed size from the end of a gzip file,
+// which is only correct for files of one member, less than 4GB long.
+func gzipSize(r io.ReadSeeker) (int64, error) {
+	if _, err := r.Seek(-4, io.SeekEnd); err != nil {
+		return 0, err
+	}
+	var size [4]byte
+	if _, err := io.ReadFull(r, size[:]); err != nil {
+		return 0, err
+	}
+	return int64(binary.LittleEndian.Uint32(size[:])), nil
+}
+
+// RegisterEncoding adds an Encoding to those searched for by
+// precompressed bundles, replacing any with the same extension.
+// The "gzip" encoding, with the ".gz" extension, is built in.
+func RegisterEncoding(enc Encoding) {
+	encodingsMu.Lock()
+	defer encodingsMu.Unlock()
+	for i := range encodings {
+		if encodings[i].Ext == enc.Ext {
+			encodings[i] = enc
+			return
+		}
+	}
+	encodings = append(encodings, enc)
+}
+
+// Encodings returns the registered encodings, in the order they
+// are searched for.
+func Encodings() []Encoding {
+	encodingsMu.RLock()
+	defer encodingsMu.RUnlock()
+	return append([]Encoding(nil), encodings...)
+}
+
+type precompressedInfo struct {
+	os.FileInfo
+	name string
+	size int64
+}
+
+func (pi *precompressedInfo) Name() string {
+	return pi.name
+}
+
+func (pi *precompressedInfo) Size() int64 {
+	return pi.size
+}
+
+// precompressedResource is a resource found through its compressed
+// sibling.
+type precompressedResource struct {
+	Resource // the compressed resource
+	path     string
+	enc      Encoding
+}
+
+func (pr *precompressedResource) Path() string {
+	return pr.path
+}
+
+func (pr *precompressedResource) String() string {
+	return pr.path
+}
+
+func (pr *precompressedResource) Open() (io.ReadCloser, error) {
+	rdr, err := pr.Resource.Open()
+	if err != nil {
+		return nil, err
+	}
+	return decompress(rdr, pr.enc)
+}
+
+// Stat reports the uncompressed size of the resource, which requires
+// decompressing it unless the size can be read from the compressed
+// data.
+func (pr *precompressedResource) Stat() (os.FileInfo, error) {
+	info, err := pr.Resource.Stat()
+	if err != nil {
+		return nil, err
+	}
+	rdr, err := pr.Resource.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rdr.Close()
+
+	var size int64
+	if rs, ok := rdr.(io.ReadSeeker); ok && pr.enc.Size != nil {
+		size, err = pr.enc.Size(rs)
+	} else {
+		var dec io.ReadCloser
+		if dec, err = pr.enc.NewReader(rdr); err == nil {
+			size, err = io.Copy(ioutil.Discard, dec)
+			dec.Close()
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &precompressedInfo{info, path.Base(pr.path), size}, nil
+}
+
+// decodingReader closes both the decompressor and the compressed
+// resource.
+type decodingReader struct {
+	io.ReadCloser
+	compressed io.Closer
+}
+
+func (dr *decodingReader) Close() error {
+	err := dr.ReadCloser.Close()
+	if cerr := dr.compressed.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func decompress(rdr io.ReadCloser, enc Encoding) (io.ReadCloser, error) {
+	dec, err := enc.NewReader(rdr)
+	if err != nil {
+		rdr.Close()
+		return nil, err
+	}
+	return &decodingReader{dec, rdr}, nil
+}
+
+type precompressedBundle struct {
+	bundle Bundle
+}
+
+// OpenPrecompressed wraps a bundle so that resources which only exist
+// in a compressed form, with the extension of a registered Encoding
+// added to their path (eg: "app.js.gz"), can be used by their
+// uncompressed path ("app.js"), and are decompressed as they are read.
+//
+// Uncompressed resources are preferred if both forms exist, and
+// compressed resources are still available by their own paths.
+//
+// The returned Bundle implements Searcher and Lister, which find
+// nothing if the wrapped bundle doesn't implement them. Closing it
+// closes the wrapped bundle.
+func OpenPrecompressed(b Bundle) Bundle {
+	return &precompressedBundle{bundle: b}
+}
+
+func (pb *precompressedBundle) wrapped() Bundle {
+	return pb.bundle
+}
+
+func (pb *precompressedBundle) Close() error {
+	return pb.bundle.Close()
+}
+
+func (pb *precompressedBundle) Open(path string) (io.ReadCloser, error) {
+	rdr, err := pb.bundle.Open(path)
+	if err != ErrNotFound {
+		return rdr, err
+	}
+	for _, enc := range Encodings() {
+		rdr, err := pb.bundle.Open(path + enc.Ext)
+		if err == ErrNotFound {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		return decompress(rdr, enc)
+	}
+	return nil, ErrNotFound
+}
+
+func (pb *precompressedBundle) Find(path string) (Resource, error) {
+	searcher, ok := pb.bundle.(Searcher)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	rsrc, err := searcher.Find(path)
+	if err != ErrNotFound {
+		return rsrc, err
+	}
+	for _, enc := range Encodings() {
+		rsrc, err := searcher.Find(path + enc.Ext)
+		if err == ErrNotFound {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		return &precompressedResource{rsrc, path, enc}, nil
+	}
+	return nil, ErrNotFound
+}
+
+// addUncompressed adds a resource for the uncompressed path of each
+// compressed resource in rsrcs which has no uncompressed form.
+func addUncompressed(rsrcs []Resource) []Resource {
+	rsrcs = rsrcs[:len(rsrcs):len(rsrcs)] // append to a copy
+	have := make(map[string]bool, len(rsrcs))
+	for _, rsrc := range rsrcs {
+		have[rsrc.Path()] = true
+	}
+	encs := Encodings()
+	for _, rsrc := range rsrcs {
+		for _, enc := range encs {
+			if !strings.HasSuffix(rsrc.Path(), enc.Ext) {
+				continue
+			}
+			path := strings.TrimSuffix(rsrc.Path(), enc.Ext)
+			if !have[path] {
+				have[path] = true
+				rsrcs = append(rsrcs, &precompressedResource{rsrc, path, enc})
+			}
+			break
+		}
+	}
+	return rsrcs
+}
+
+// Glob matches the pattern against both uncompressed paths, and the
+// uncompressed paths of compressed resources.
+func (pb *precompressedBundle) Glob(pattern string) ([]Resource, error) {
+	searcher, ok := pb.bundle.(Searcher)
+	if !ok {
+		return nil, nil
+	}
+	rsrcs, err := searcher.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+	rm := newResourceMerge()
+	rm.add(0, rsrcs)
+	for _, enc := range Encodings() {
+		compressed, err := searcher.Glob(pattern + enc.Ext)
+		if err != nil {
+			return nil, err
+		}
+		for _, rsrc := range addUncompressed(compressed) {
+			if p, ok := rsrc.(*precompressedResource); ok {
+				rm.add(0, []Resource{p})
+			}
+		}
+	}
+	return rm.list, nil
+}
+
+func (pb *precompressedBundle) List() ([]Resource, error) {
+	lister, ok := pb.bundle.(Lister)
+	if !ok {
+		return nil, nil
+	}
+	rsrcs, err := lister.List()
+	if err != nil {
+		return nil, err
+	}
+	return addUncompressed(rsrcs), nil
+}
@@ -0,0 +1,67 @@
+package resources
+
+import (
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	. "testing"
+)
+
+func writeGzip(t *T, name, contents string) {
+	f, err := os.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gw := gzip.NewWriter(f)
+	gw.Write([]byte(contents))
+	if err := gw.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPrecompressed(t *T) {
+	dir := t.TempDir()
+	app := strings.Repeat("console.log('app');\n", 50)
+	writeGzip(t, filepath.Join(dir, "app.js.gz"), app)
+	writeGzip(t, filepath.Join(dir, "both.txt.gz"), "compressed")
+	os.WriteFile(filepath.Join(dir, "both.txt"), []byte("plain"), 0644)
+
+	pb := OpenPrecompressed(OpenDir(dir))
+	if got := readAll(t, pb, "app.js"); got != app {
+		t.Errorf("Open(app.js) = %q", got)
+	}
+	if got := readAll(t, pb, "both.txt"); got != "plain" {
+		t.Errorf("Open(both.txt) = %q, want the uncompressed form", got)
+	}
+	if _, err := pb.Open("missing.js"); err != ErrNotFound {
+		t.Errorf("Open(missing.js): %v, want ErrNotFound", err)
+	}
+
+	rsrc, err := pb.(Searcher).Find("app.js")
+	if err != nil {
+		t.Fatal("Find(app.js):", err)
+	}
+	if info, err := rsrc.Stat(); err != nil || info.Size() != int64(len(app)) || info.Name() != "app.js" {
+		t.Errorf("Stat(app.js) = %v, %v; want size %d", info, err, len(app))
+	}
+
+	list, err := pb.(Lister).List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	for _, rsrc := range list {
+		paths = append(paths, rsrc.Path())
+	}
+	sort.Strings(paths)
+	if got, want := strings.Join(paths, " "), "app.js app.js.gz both.txt both.txt.gz"; got != want {
+		t.Errorf("List() = %s, want %s", got, want)
+	}
+
+	if globbed, err := pb.(Searcher).Glob("*.js"); err != nil || len(globbed) != 1 {
+		t.Errorf("Glob(*.js) = %v, %v", globbed, err)
+	}
+}
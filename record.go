@@ -0,0 +1,102 @@
+package resources
+
+import (
+	"bufio"
+	"os"
+	"sync"
+)
+
+// A Recorder is an Observer which appends the path of each resource
+// successfully opened or found to a file, once per path. Lines are
+// appended with single writes to a file opened for appending, so many
+// processes can record to the same file, which is useful for finding
+// the resources a program really uses, eg: with the resources-shake
+// command.
+type Recorder struct {
+	mu   sync.Mutex
+	file *os.File
+	seen map[string]bool
+	err  error
+}
+
+// NewRecorder returns a Recorder appending to the file at path,
+// creating it if needed.
+func NewRecorder(path string) (*Recorder, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &Recorder{file: file, seen: make(map[string]bool)}, nil
+}
+
+// Middleware returns a Middleware recording the use of the resources
+// of the bundles it wraps.
+func (r *Recorder) Middleware() Middleware {
+	return Instrument(r)
+}
+
+// Begin implements the Observer interface.
+func (r *Recorder) Begin(op Op, path string) func(error) {
+	if op != OpOpen && op != OpFind {
+		return func(error) {}
+	}
+	return func(err error) {
+		if err == nil {
+			r.record(path)
+		}
+	}
+}
+
+// Read implements the Observer interface.
+func (r *Recorder) Read(path string, n int64, err error) {}
+
+func (r *Recorder) record(path string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.seen[path] || r.file == nil {
+		return
+	}
+	r.seen[path] = true
+	if _, err := r.file.WriteString(path + "\n"); err != nil && r.err == nil {
+		r.err = err
+	}
+}
+
+// Close closes the file, returning the first error writing to it, if
+// any. Later uses of the resources aren't recorded.
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == nil {
+		return r.err
+	}
+	if err := r.file.Close(); r.err == nil {
+		r.err = err
+	}
+	r.file = nil
+	return r.err
+}
+
+// ReadRecordings returns the set of paths recorded in the files by
+// Recorders.
+func ReadRecordings(files ...string) (map[string]bool, error) {
+	used := make(map[string]bool)
+	for _, name := range files {
+		file, err := os.Open(name)
+		if err != nil {
+			return nil, err
+		}
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			if line := scanner.Text(); line != "" {
+				used[line] = true
+			}
+		}
+		err = scanner.Err()
+		file.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return used, nil
+}
@@ -0,0 +1,45 @@
+package resources
+
+import (
+	"path/filepath"
+	. "testing"
+)
+
+func TestRecorder(t *T) {
+	zr := CreateTestZip(t)
+	zb, err := OpenZipReader(zr, int64(zr.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(t.TempDir(), "used.txt")
+
+	// Two recorders append to the same file, as separate processes would.
+	for i := 0; i < 2; i++ {
+		rec, err := NewRecorder(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b := rec.Middleware()(zb)
+		readAll(t, b, "foo.txt")
+		readAll(t, b, "foo.txt")
+		b.Open("missing")
+		if i == 1 {
+			if _, err := b.(Searcher).Find("subfolder/bar.txt"); err != nil {
+				t.Fatal(err)
+			}
+			b.(Lister).List()
+		}
+		if err := rec.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	used, err := ReadRecordings(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Log(used)
+	if len(used) != 2 || !used["foo.txt"] || !used["subfolder/bar.txt"] {
+		t.Errorf("ReadRecordings() = %v, want foo.txt and subfolder/bar.txt", used)
+	}
+}
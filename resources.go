@@ -1,6 +1,7 @@
 package resources
 
 import (
+	"context"
 	"io"
 	"os"
 )
@@ -60,3 +61,65 @@ type Searcher interface {
 type Lister interface {
 	List() ([]Resource, error)
 }
+
+// A ContextOpener is a bundle whose Open can be cancelled, eg: an HTTP
+// bundle. A Sequence searching layers in parallel cancels the Opens of
+// lower priority layers once a higher priority one has the resource.
+type ContextOpener interface {
+	// OpenContext is like Open, but gives up when ctx is done. The
+	// reader may stop working then too.
+	OpenContext(ctx context.Context, path string) (io.ReadCloser, error)
+}
+
+// openContext opens path in b, with ctx if b is a ContextOpener.
+func openContext(ctx context.Context, b Bundle, path string) (io.ReadCloser, error) {
+	if co, ok := b.(ContextOpener); ok {
+		return co.OpenContext(ctx, path)
+	}
+	return b.Open(path)
+}
+
+// A Layered bundle searches other bundles, its layers, in order of
+// priority, eg: a BundleSequence. Code which merges resources by name,
+// such as template loaders, can use the layers to let resources in one
+// override those in later ones.
+type Layered interface {
+	// Bundles returns the layers, highest priority first, or nil if
+	// there are none, eg: for a wrapper of a bundle which isn't
+	// Layered. The returned slice must not be modified.
+	Bundles() []Bundle
+}
+
+// layersOf returns the layers of b, or nil if it isn't Layered.
+func layersOf(b Bundle) []Bundle {
+	if l, ok := b.(Layered); ok {
+		return l.Bundles()
+	}
+	return nil
+}
+
+// A wrapper is a bundle wrapping another, whose Find and Glob find
+// nothing if the bundle it wraps doesn't implement Searcher.
+type wrapper interface {
+	// wrapped returns the wrapped bundle, or nil if there is none.
+	wrapped() Bundle
+}
+
+// searchable reports whether the Find of b finds every resource its
+// Open does: b implements Searcher, and so do the bundles it wraps and
+// its layers.
+func searchable(b Bundle) bool {
+	if _, ok := b.(Searcher); !ok {
+		return false
+	}
+	if w, ok := b.(wrapper); ok {
+		inner := w.wrapped()
+		return inner == nil || searchable(inner)
+	}
+	for _, layer := range layersOf(b) {
+		if layer != nil && !searchable(layer) {
+			return false
+		}
+	}
+	return true
+}
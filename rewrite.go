@@ -0,0 +1,290 @@
+package resources
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"regexp"
+	"strings"
+)
+
+type rewriteKind int
+
+const (
+	rewriteAlias rewriteKind = iota
+	rewritePrefix
+	rewriteRegexp
+)
+
+type rewriteRule struct {
+	kind     rewriteKind
+	from, to string
+	re       *regexp.Regexp
+}
+
+// apply rewrites p, if the rule matches it.
+func (r *rewriteRule) apply(p string) (string, bool) {
+	switch r.kind {
+	case rewriteAlias:
+		return r.to, p == r.from
+	case rewritePrefix:
+		if strings.HasPrefix(p, r.from) {
+			return r.to + p[len(r.from):], true
+		}
+	case rewriteRegexp:
+		if r.re.MatchString(p) {
+			return r.re.ReplaceAllString(p, r.to), true
+		}
+	}
+	return "", false
+}
+
+// reverse returns the path which the rule rewrites to p, if there is
+// one and it can be found.
+func (r *rewriteRule) reverse(p string) (string, bool) {
+	switch r.kind {
+	case rewriteAlias:
+		return r.from, p == r.to
+	case rewritePrefix:
+		if strings.HasPrefix(p, r.to) {
+			return r.from + p[len(r.to):], true
+		}
+	}
+	return "", false
+}
+
+// A Rewriter maps the paths of resources to other paths, eg: so old
+// paths keep working after resources are moved. Rules are tried in
+// the order they were added, and the first matching rule rewrites the
+// path. Paths no rule matches are left alone.
+type Rewriter struct {
+	rules []*rewriteRule
+
+	// ListAliases makes bundles using the Rewriter list, and glob,
+	// the paths which alias and prefix rules rewrite to resources
+	// of the bundle, as well as their real paths. Paths rewritten
+	// by regular expressions can't be listed.
+	ListAliases bool
+}
+
+// Alias rewrites the path from to the path to.
+func (rw *Rewriter) Alias(from, to string) {
+	rw.rules = append(rw.rules, &rewriteRule{kind: rewriteAlias, from: from, to: to})
+}
+
+// Prefix rewrites paths starting with from to start with to instead,
+// eg: Prefix("old/", "new/") rewrites "old/a.png" to "new/a.png".
+func (rw *Rewriter) Prefix(from, to string) {
+	rw.rules = append(rw.rules, &rewriteRule{kind: rewritePrefix, from: from, to: to})
+}
+
+// Regexp rewrites paths wholly matching the regular expression
+// pattern to replacement, in which $1 etc. are replaced by the
+// submatches, as in regexp.Regexp.Expand.
+func (rw *Rewriter) Regexp(pattern, replacement string) error {
+	re, err := regexp.Compile("^(?:" + pattern + ")$")
+	if err != nil {
+		return err
+	}
+	rw.rules = append(rw.rules, &rewriteRule{kind: rewriteRegexp, re: re, to: replacement})
+	return nil
+}
+
+// Rewrite returns the path p is rewritten to, and whether a rule
+// matched it.
+func (rw *Rewriter) Rewrite(p string) (string, bool) {
+	for _, rule := range rw.rules {
+		if to, ok := rule.apply(p); ok {
+			return to, true
+		}
+	}
+	return p, false
+}
+
+// aliases returns the paths rewritten to p, which no earlier rule
+// rewrites elsewhere.
+func (rw *Rewriter) aliases(p string) []string {
+	var aliases []string
+	for _, rule := range rw.rules {
+		if from, ok := rule.reverse(p); ok {
+			if to, _ := rw.Rewrite(from); to == p {
+				aliases = append(aliases, from)
+			}
+		}
+	}
+	return aliases
+}
+
+// ParseRewrite parses rules, one per line, each being a kind, and its
+// two arguments, separated by spaces:
+//
+//	# comments and blank lines are skipped
+//	alias  old/logo.png  img/logo.png
+//	prefix old/sounds/   audio/
+//	regexp levels/(\d+)\.map  maps/level$1.map
+//
+// The line "list-aliases" sets ListAliases.
+func ParseRewrite(text string) (*Rewriter, error) {
+	rw := new(Rewriter)
+	for i, line := range strings.Split(text, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if len(fields) == 1 && fields[0] == "list-aliases" {
+			rw.ListAliases = true
+			continue
+		}
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("resources: rewrite rule %d: want kind, from and to", i+1)
+		}
+		switch fields[0] {
+		case "alias":
+			rw.Alias(fields[1], fields[2])
+		case "prefix":
+			rw.Prefix(fields[1], fields[2])
+		case "regexp":
+			if err := rw.Regexp(fields[1], fields[2]); err != nil {
+				return nil, fmt.Errorf("resources: rewrite rule %d: %v", i+1, err)
+			}
+		default:
+			return nil, fmt.Errorf("resources: rewrite rule %d: unknown kind %q", i+1, fields[0])
+		}
+	}
+	return rw, nil
+}
+
+// aliasResource is a resource used by a path rewritten to its own.
+type aliasResource struct {
+	Resource
+	path string
+}
+
+func (ar *aliasResource) Path() string {
+	return ar.path
+}
+
+func (ar *aliasResource) String() string {
+	return ar.path
+}
+
+type rewriteBundle struct {
+	bundle Bundle
+	rw     *Rewriter
+}
+
+// OpenRewrite wraps a bundle so the paths given to its Open and Find
+// are rewritten by rw before being used. Resources found by rewritten
+// paths report the path they were found by. Resources whose own
+// paths are rewritten can't be used by them.
+//
+// The returned Bundle implements Searcher and Lister, which find
+// nothing if the wrapped bundle doesn't implement them. Closing it
+// closes the wrapped bundle.
+func OpenRewrite(b Bundle, rw *Rewriter) Bundle {
+	return &rewriteBundle{b, rw}
+}
+
+// LoadRewrite opens b with OpenRewrite, using the rules in the
+// resource at rules_path of b, as parsed by ParseRewrite.
+func LoadRewrite(b Bundle, rules_path string) (Bundle, error) {
+	rdr, err := b.Open(rules_path)
+	if err != nil {
+		return nil, err
+	}
+	defer rdr.Close()
+	data, err := ioutil.ReadAll(rdr)
+	if err != nil {
+		return nil, err
+	}
+	rw, err := ParseRewrite(string(data))
+	if err != nil {
+		return nil, err
+	}
+	return OpenRewrite(b, rw), nil
+}
+
+func (rb *rewriteBundle) wrapped() Bundle {
+	return rb.bundle
+}
+
+func (rb *rewriteBundle) Close() error {
+	return rb.bundle.Close()
+}
+
+func (rb *rewriteBundle) Open(path string) (io.ReadCloser, error) {
+	to, _ := rb.rw.Rewrite(path)
+	return rb.bundle.Open(to)
+}
+
+func (rb *rewriteBundle) Find(path string) (Resource, error) {
+	searcher, ok := rb.bundle.(Searcher)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	to, rewritten := rb.rw.Rewrite(path)
+	rsrc, err := searcher.Find(to)
+	if err != nil || !rewritten {
+		return rsrc, err
+	}
+	return &aliasResource{rsrc, path}, nil
+}
+
+// visible returns the resources which can be used by their own paths,
+// and, with ListAliases, by the paths rewritten to them.
+func (rb *rewriteBundle) visible(rsrcs []Resource) []Resource {
+	var out []Resource
+	for _, rsrc := range rsrcs {
+		if _, rewritten := rb.rw.Rewrite(rsrc.Path()); !rewritten {
+			out = append(out, rsrc)
+		}
+		if rb.rw.ListAliases {
+			for _, alias := range rb.rw.aliases(rsrc.Path()) {
+				out = append(out, &aliasResource{rsrc, alias})
+			}
+		}
+	}
+	return out
+}
+
+func (rb *rewriteBundle) Glob(pattern string) ([]Resource, error) {
+	searcher, ok := rb.bundle.(Searcher)
+	if !ok {
+		return nil, nil
+	}
+	rsrcs, err := searcher.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := rb.bundle.(Lister); !rb.rw.ListAliases || !ok {
+		return rb.visible(rsrcs), nil
+	}
+
+	// Aliases may match the pattern when their targets don't.
+	all, err := rb.List()
+	if err != nil {
+		return nil, err
+	}
+	var matches []Resource
+	for _, rsrc := range all {
+		if match, err := path.Match(pattern, rsrc.Path()); err != nil {
+			return nil, err
+		} else if match {
+			matches = append(matches, rsrc)
+		}
+	}
+	return matches, nil
+}
+
+func (rb *rewriteBundle) List() ([]Resource, error) {
+	lister, ok := rb.bundle.(Lister)
+	if !ok {
+		return nil, nil
+	}
+	rsrcs, err := lister.List()
+	if err != nil {
+		return nil, err
+	}
+	return rb.visible(rsrcs), nil
+}
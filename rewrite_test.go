@@ -0,0 +1,89 @@
+package resources
+
+import (
+	"sort"
+	. "testing"
+)
+
+var rewriteBundle_Is_A_Searcher Searcher = &rewriteBundle{}
+var rewriteBundle_Is_A_Lister Lister = &rewriteBundle{}
+
+func paths(rsrcs []Resource) []string {
+	var ps []string
+	for _, rsrc := range rsrcs {
+		ps = append(ps, rsrc.Path())
+	}
+	sort.Strings(ps)
+	return ps
+}
+
+func TestRewrite(t *T) {
+	zb := zipOfFiles(t, map[string]string{
+		"rules.txt": `
+# renamed in 2.0
+alias  logo.png      img/logo.png
+prefix sounds/       audio/
+regexp levels/(\d+)\.map  maps/level$1.map
+list-aliases
+`,
+		"img/logo.png":    "logo",
+		"audio/hit.wav":   "hit",
+		"maps/level3.map": "level 3",
+		"sounds/old.wav":  "shadowed",
+	})
+	b, err := LoadRewrite(zb, "rules.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for from, want := range map[string]string{
+		"logo.png":        "logo",
+		"img/logo.png":    "logo",
+		"sounds/hit.wav":  "hit",
+		"levels/3.map":    "level 3",
+		"maps/level3.map": "level 3",
+	} {
+		if got := readAll(t, b, from); got != want {
+			t.Errorf("Open(%s) = %q, want %q", from, got, want)
+		}
+	}
+	if _, err := b.Open("sounds/old.wav"); err != ErrNotFound {
+		t.Errorf("Open(sounds/old.wav) = %v, want ErrNotFound", err)
+	}
+
+	rsrc, err := b.(Searcher).Find("levels/3.map")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rsrc.Path() != "levels/3.map" {
+		t.Errorf("Find(levels/3.map).Path() = %q", rsrc.Path())
+	}
+
+	list, err := b.(Lister).List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := paths(list)
+	t.Log(got)
+	want := []string{"audio/hit.wav", "img/logo.png", "logo.png", "maps/level3.map", "rules.txt", "sounds/hit.wav"}
+	if len(got) != len(want) {
+		t.Fatalf("List() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("List()[%d] = %s, want %s", i, got[i], want[i])
+		}
+	}
+
+	matches, err := b.(Searcher).Glob("sounds/*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := paths(matches); len(got) != 1 || got[0] != "sounds/hit.wav" {
+		t.Errorf("Glob(sounds/*) = %v, want [sounds/hit.wav]", got)
+	}
+
+	if _, err := ParseRewrite("bogus a b"); err == nil {
+		t.Error("ParseRewrite(bogus) succeeded")
+	}
+}
@@ -0,0 +1,242 @@
+package resources
+
+import (
+	"io"
+	"iter"
+	"reflect"
+	"sync"
+	"sync/atomic"
+)
+
+// A SearchPath is a BundleSequence which can be safely modified while
+// other goroutines use it. It is searched by a Sequence of its bundles,
+// which can be configured with Configure, eg: to use another Strategy.
+//
+// Modifications copy the sequence, so a snapshot taken with Snapshot
+// never changes, and lookups never wait for writers. The zero value is
+// an empty SearchPath ready to use.
+type SearchPath struct {
+	mu  sync.Mutex // serializes writers
+	seq atomic.Pointer[Sequence]
+}
+
+// NewSearchPath returns a SearchPath containing the given bundles.
+func NewSearchPath(bundles ...Bundle) *SearchPath {
+	sp := new(SearchPath)
+	sp.Set(bundles...)
+	return sp
+}
+
+// Snapshot returns the current sequence of bundles. The returned
+// sequence must not be modified.
+func (sp *SearchPath) Snapshot() BundleSequence {
+	return sp.sequence().Layers
+}
+
+// sequence returns the current Sequence searching the bundles.
+func (sp *SearchPath) sequence() *Sequence {
+	if seq := sp.seq.Load(); seq != nil {
+		return seq
+	}
+	return new(Sequence)
+}
+
+// update replaces the bundles with the result of calling fn on a
+// copy of the current ones.
+func (sp *SearchPath) update(fn func(BundleSequence) BundleSequence) {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	old := sp.sequence()
+	seq := *old
+	seq.Layers = fn(append(BundleSequence(nil), old.Layers...))
+	sp.seq.Store(&seq)
+}
+
+// Configure changes how the bundles are searched, by calling fn with a
+// copy of the Sequence searching them, which is used from then on, eg:
+//
+//	sp.Configure(func(s *resources.Sequence) {
+//		s.Strategy = resources.NewestWins
+//		s.Lookups = resources.NewLookupCache(time.Minute)
+//	})
+//
+// Changes to the Layers of the Sequence are ignored; use Set, Prepend,
+// Append or Remove. The Lookups are invalidated by changes to the
+// bundles, as they are a new slice of layers, and by Configure, which
+// may change the Strategy. Searches already started aren't affected.
+func (sp *SearchPath) Configure(fn func(*Sequence)) {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	old := sp.sequence()
+	seq := *old
+	fn(&seq)
+	seq.Layers = old.Layers
+	sp.seq.Store(&seq)
+	if seq.Lookups != nil {
+		seq.Lookups.InvalidateAll()
+	}
+}
+
+// Set replaces all the bundles in the search path.
+func (sp *SearchPath) Set(bundles ...Bundle) {
+	sp.update(func(BundleSequence) BundleSequence {
+		return append(BundleSequence(nil), bundles...)
+	})
+}
+
+// Prepend adds bundles to the front of the search path, where they
+// take priority over the existing bundles.
+func (sp *SearchPath) Prepend(bundles ...Bundle) {
+	sp.update(func(seq BundleSequence) BundleSequence {
+		return append(append(BundleSequence(nil), bundles...), seq...)
+	})
+}
+
+// Append adds bundles to the end of the search path.
+func (sp *SearchPath) Append(bundles ...Bundle) {
+	sp.update(func(seq BundleSequence) BundleSequence {
+		return append(seq, bundles...)
+	})
+}
+
+// sameBundle reports whether a and b are the same bundle. Bundles of
+// uncomparable types (such as BundleSequences) are never the same, so
+// they can't be removed with Remove.
+func sameBundle(a, b Bundle) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	ta, tb := reflect.TypeOf(a), reflect.TypeOf(b)
+	return ta == tb && ta.Comparable() && a == b
+}
+
+// Remove removes the first occurrence of bundle from the search path,
+// and reports whether it was found. Bundles of uncomparable types,
+// such as BundleSequences, are never found; use WithOverride to add
+// them temporarily.
+func (sp *SearchPath) Remove(bundle Bundle) (found bool) {
+	sp.update(func(seq BundleSequence) BundleSequence {
+		for i, b := range seq {
+			if sameBundle(b, bundle) {
+				found = true
+				return append(seq[:i], seq[i+1:]...)
+			}
+		}
+		return seq
+	})
+	return
+}
+
+// WithOverride puts bundle at the front of the search path, so its
+// resources shadow those of the other bundles, until restore is
+// called. It is intended for tests, which can replace individual
+// resources with:
+//
+//	restore := sp.WithOverride(testBundle)
+//	defer restore()
+//
+// Calling restore more than once has no further effect. The bundle is
+// added wrapped, so that restore removes it even if it is of an
+// uncomparable type, such as a BundleSequence, or was also added by
+// other means.
+func (sp *SearchPath) WithOverride(bundle Bundle) (restore func()) {
+	o := &override{bundle}
+	sp.Prepend(o)
+	var once sync.Once
+	return func() {
+		once.Do(func() { sp.Remove(o) })
+	}
+}
+
+// An override is a bundle added by WithOverride. Its pointer identifies
+// it, whatever the bundle.
+type override struct {
+	bundle Bundle
+}
+
+func (o *override) Open(path string) (io.ReadCloser, error) {
+	return o.bundle.Open(path)
+}
+
+func (o *override) Find(path string) (Resource, error) {
+	if searcher, ok := o.bundle.(Searcher); ok {
+		return searcher.Find(path)
+	}
+	return nil, ErrNotFound
+}
+
+func (o *override) Glob(pattern string) ([]Resource, error) {
+	if searcher, ok := o.bundle.(Searcher); ok {
+		return searcher.Glob(pattern)
+	}
+	return nil, nil
+}
+
+func (o *override) List() ([]Resource, error) {
+	if lister, ok := o.bundle.(Lister); ok {
+		return lister.List()
+	}
+	return nil, nil
+}
+
+func (o *override) wrapped() Bundle {
+	return o.bundle
+}
+
+// Bundles implements the Layered interface, returning the layers of
+// the bundle.
+func (o *override) Bundles() []Bundle {
+	return layersOf(o.bundle)
+}
+
+// Close is a no-op: the caller of WithOverride owns the bundle.
+func (o *override) Close() error {
+	return nil
+}
+
+// Close() is a no-op for SearchPaths; you must close the
+// bundles yourself.
+func (sp *SearchPath) Close() error {
+	return nil
+}
+
+// Bundles implements the Layered interface, returning the current
+// sequence of bundles.
+func (sp *SearchPath) Bundles() []Bundle {
+	return sp.Snapshot()
+}
+
+// Open is the same as Sequence.Open, on the current bundles.
+func (sp *SearchPath) Open(path string) (io.ReadCloser, error) {
+	return sp.sequence().Open(path)
+}
+
+// Find is the same as Sequence.Find, on the current bundles.
+func (sp *SearchPath) Find(path string) (Resource, error) {
+	return sp.sequence().Find(path)
+}
+
+// FindAll is the same as Sequence.FindAll, on the current bundles.
+func (sp *SearchPath) FindAll(path string) ([]Resource, error) {
+	return sp.sequence().FindAll(path)
+}
+
+// Glob is the same as Sequence.Glob, on the current bundles.
+func (sp *SearchPath) Glob(pattern string) ([]Resource, error) {
+	return sp.sequence().Glob(pattern)
+}
+
+// List is the same as Sequence.List, on the current bundles.
+func (sp *SearchPath) List() ([]Resource, error) {
+	return sp.sequence().List()
+}
+
+// All is the same as Sequence.All, on the current bundles.
+func (sp *SearchPath) All() iter.Seq2[Resource, error] {
+	return sp.sequence().All()
+}
+
+// Shadows is the same as BundleSequence.Shadows on a snapshot of sp.
+func (sp *SearchPath) Shadows() ([]Shadow, error) {
+	return sp.Snapshot().Shadows()
+}
@@ -0,0 +1,116 @@
+package resources
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	. "testing"
+)
+
+var SearchPath_Is_A_Bundle Bundle = &SearchPath{}
+var SearchPath_Is_A_Searcher Searcher = &SearchPath{}
+var SearchPath_Is_A_Lister Lister = &SearchPath{}
+
+// zipOf returns a zip bundle containing a single file.
+func zipOf(t *T, path, contents string) Bundle {
+	return zipOfFiles(t, map[string]string{path: contents})
+}
+
+// zipOfFiles returns a zip bundle of files, mapping paths to contents.
+func zipOfFiles(t *T, files map[string]string) Bundle {
+	buf := new(bytes.Buffer)
+	zw := zip.NewWriter(buf)
+	for p, contents := range files {
+		if fw, err := zw.Create(p); err != nil {
+			t.Fatal(err)
+		} else if _, err := fw.Write([]byte(contents)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	zb, err := OpenZipReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return zb
+}
+
+func readAll(t *T, b Bundle, path string) string {
+	rdr, err := b.Open(path)
+	if err != nil {
+		t.Fatalf("Open(%q): %v", path, err)
+	}
+	defer rdr.Close()
+	data, err := ioutil.ReadAll(rdr)
+	if err != nil {
+		t.Fatalf("ReadAll(%q): %v", path, err)
+	}
+	return string(data)
+}
+
+func TestSearchPath(t *T) {
+	a, b, c := zipOf(t, "x", "a"), zipOf(t, "x", "b"), zipOf(t, "x", "c")
+	sp := NewSearchPath(b)
+	sp.Append(c)
+	sp.Prepend(a)
+
+	snapshot := sp.Snapshot()
+	if got := readAll(t, sp, "x"); got != "a" {
+		t.Errorf("Open(x) = %q, want %q", got, "a")
+	}
+	if !sp.Remove(a) {
+		t.Error("Remove(a) = false")
+	}
+	if sp.Remove(a) {
+		t.Error("second Remove(a) = true")
+	}
+	if got := readAll(t, sp, "x"); got != "b" {
+		t.Errorf("Open(x) after Remove(a) = %q, want %q", got, "b")
+	}
+	if len(snapshot) != 3 {
+		t.Errorf("snapshot changed, len = %d", len(snapshot))
+	}
+}
+
+func TestSearchPathOverride(t *T) {
+	sp := NewSearchPath(zipOf(t, "x", "base"))
+	for i := 0; i < 8; i++ {
+		i := i
+		t.Run(fmt.Sprint(i), func(t *T) {
+			t.Parallel()
+			name := fmt.Sprintf("override%d", i)
+			restore := sp.WithOverride(zipOf(t, name, name))
+			defer restore()
+
+			if got := readAll(t, sp, name); got != name {
+				t.Errorf("Open(%s) = %q", name, got)
+			}
+			if got := readAll(t, sp, "x"); got != "base" {
+				t.Errorf("Open(x) = %q, want base", got)
+			}
+		})
+	}
+	t.Cleanup(func() {
+		if n := len(sp.Snapshot()); n != 1 {
+			t.Errorf("len(Snapshot()) = %d after restores, want 1", n)
+		}
+	})
+}
+
+func TestSearchPathOverrideSequence(t *T) {
+	sp := NewSearchPath(zipOf(t, "x", "base"))
+	restore := sp.WithOverride(BundleSequence{zipOf(t, "x", "override")})
+	if got := readAll(t, sp, "x"); got != "override" {
+		t.Errorf("Open(x) = %q, want override", got)
+	}
+	restore()
+	if got := readAll(t, sp, "x"); got != "base" {
+		t.Errorf("Open(x) after restore = %q, want base", got)
+	}
+	if n := len(sp.Snapshot()); n != 1 {
+		t.Errorf("len(Snapshot()) = %d after restore, want 1", n)
+	}
+}
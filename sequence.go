@@ -2,13 +2,19 @@ package resources
 
 import (
 	"io"
-	"path/filepath"
+	"iter"
+	"sort"
 )
 
 // BundleSequences are meta-bundles which contain a slice
 // of sub-bundles to test/access sequentially for resources.
 //
 // Nil bundles are skipped, instead of causing errors or panics.
+//
+// The resource of the first sub-bundle which has one is always used,
+// and the first error of a sub-bundle is returned, as by a Sequence of
+// them with the default settings; see Sequence for other strategies
+// and error policies, and SearchPath to configure them.
 type BundleSequence []Bundle
 
 // Close() is a no-op for BundleSequences; you must close
@@ -17,23 +23,24 @@ func (bs BundleSequence) Close() error {
 	return nil
 }
 
+// Bundles implements the Layered interface.
+func (bs BundleSequence) Bundles() []Bundle {
+	return bs
+}
+
+// sequence returns a Sequence of the sub-bundles, with the default
+// settings, which searches them as documented by the methods of a
+// BundleSequence.
+func (bs BundleSequence) sequence() *Sequence {
+	return &Sequence{Layers: bs}
+}
+
 // Open finds the first sub-bundle where Open() doesn't return
 // a ErrNotFound, and returns the io.ReadCloser.
 //
 // If any error other than ErrNotFound is seen, it is returned immediately.
 func (bs BundleSequence) Open(path string) (io.ReadCloser, error) {
-	for _, bundle := range bs {
-		if bundle == nil {
-			continue
-		}
-		reader, err := bundle.Open(path)
-		if err == nil {
-			return reader, nil
-		} else if err != ErrNotFound {
-			return nil, err
-		}
-	}
-	return nil, ErrNotFound
+	return bs.sequence().Open(path)
 }
 
 // Find finds the first resource matching path in the sub-bundles.
@@ -42,69 +49,99 @@ func (bs BundleSequence) Open(path string) (io.ReadCloser, error) {
 //
 // If any error other than ErrNotFound is seen, it is returned.
 func (bs BundleSequence) Find(path string) (Resource, error) {
-	for _, bundle := range bs {
-		if bundle == nil {
-			continue
-		}
-		if searchable, ok := bundle.(Searcher); ok {
-			resource, err := searchable.Find(path)
-			if err == nil {
-				return resource, nil
-			} else if err != ErrNotFound {
-				return nil, err
-			}
-		}
-	}
-	return nil, ErrNotFound
-}
-
-// merge_resources merges two lists of resources and returns
-// the resulting list. This operation is analgeous to append
-// but doesn't append an element from extra into source if
-// an element in source already exists with the same path.
-func merge_resources(source, extra []Resource) []Resource {
-	for _, e := range extra {
-		found := false
-		for _, s := range source {
-			if s.Path() == e.Path() {
-				found = true
-				break
+	return bs.sequence().Find(path)
+}
+
+// FindAll finds the resource at path in every sub-bundle which has
+// one, in order, so the first is the one Find would return. If no
+// sub-bundle has the resource, ErrNotFound is returned.
+//
+// If any error other than ErrNotFound is seen, it is returned.
+func (bs BundleSequence) FindAll(path string) ([]Resource, error) {
+	return bs.sequence().FindAll(path)
+}
+
+// A resourceMerge merges the resources of several layers, keeping the
+// first resource with each path, as they're added layer by layer.
+type resourceMerge struct {
+	layers map[string]int // path -> layer of the kept resource
+	list   []Resource     // kept resources, in the order added
+
+	// shadowed, if not nil, is called with each resource which isn't
+	// kept, the index of its layer, and of the layer of the one kept.
+	shadowed func(rsrc Resource, layer, by int)
+}
+
+func newResourceMerge() *resourceMerge {
+	return &resourceMerge{layers: make(map[string]int)}
+}
+
+// add merges the resources of a layer.
+func (rm *resourceMerge) add(layer int, rsrcs []Resource) {
+	for _, rsrc := range rsrcs {
+		p := rsrc.Path()
+		if by, ok := rm.layers[p]; ok {
+			if rm.shadowed != nil {
+				rm.shadowed(rsrc, layer, by)
 			}
+			continue
 		}
-		if !found {
-			source = append(source, e)
-		}
+		rm.layers[p] = layer
+		rm.list = append(rm.list, rsrc)
 	}
-	return source
+}
+
+// sorted returns the kept resources sorted by path.
+func (rm *resourceMerge) sorted() []Resource {
+	sort.Slice(rm.list, func(i, j int) bool {
+		return rm.list[i].Path() < rm.list[j].Path()
+	})
+	return rm.list
 }
 
 // Glob finds the collection of all resources in all the sub-bundles
-// which match the given glob pattern.
+// which match the given glob pattern, sorted by path.
 // In the event that multiple resources matched have the same path,
 // the one from the earliest sub-bundle will be shown, all others
-// will be suppressed.
-func (bs BundleSequence) Glob(pattern string) (matches []Resource, err error) {
-	for _, bundle := range bs {
-		if bundle == nil {
-			continue
-		}
-		if searchable, ok := bundle.(Searcher); ok {
-			resources, err := searchable.Glob(pattern)
-			if err == nil {
-				matches = merge_resources(matches, resources)
-			} else if err != ErrNotFound {
-				return nil, err
-			}
-		}
-	}
-	return
+// will be suppressed. Like List, the whole result is built in memory.
+func (bs BundleSequence) Glob(pattern string) ([]Resource, error) {
+	return bs.sequence().Glob(pattern)
 }
 
-// List provides a slice containing all resources from all the sub-bundles.
+// List provides a slice containing all resources from all the sub-bundles,
+// sorted by path.
 // Should multiple bundles contain a resource at the same path, only the
 // first resource (from the first sub-bundle) will be present in the list.
-func (bs BundleSequence) List() (resources []Resource, err error) {
-	for _, bundle := range bs {
+// The whole list is built in memory; use All to merge it as it's used.
+func (bs BundleSequence) List() ([]Resource, error) {
+	return bs.sequence().List()
+}
+
+// All returns an iterator over the resources List returns, merging the
+// listings of the sub-bundles as it is used (see Sequence.All).
+func (bs BundleSequence) All() iter.Seq2[Resource, error] {
+	return bs.sequence().All()
+}
+
+// A Shadow is a resource of a sub-bundle of a sequence which is hidden
+// by a resource with the same path in an earlier sub-bundle.
+type Shadow struct {
+	Resource Resource // the hidden resource
+	Layer    int      // index of its sub-bundle
+	By       int      // index of the sub-bundle hiding it
+}
+
+// Shadows lists the resources of the sub-bundles implementing Lister
+// which are hidden by earlier sub-bundles, sorted by path, then by
+// sub-bundle. It helps to find overrides which are no longer needed,
+// or which hide resources by mistake.
+func (bs BundleSequence) Shadows() ([]Shadow, error) {
+	var shadows []Shadow
+	rm := newResourceMerge()
+	rm.shadowed = func(rsrc Resource, layer, by int) {
+		shadows = append(shadows, Shadow{rsrc, layer, by})
+	}
+	for i, bundle := range bs {
 		if bundle == nil {
 			continue
 		}
@@ -113,50 +150,72 @@ func (bs BundleSequence) List() (resources []Resource, err error) {
 			if err != nil {
 				return nil, err
 			}
-			resources = merge_resources(resources, list)
+			rm.add(i, list)
 		}
 	}
-	return
+	sort.SliceStable(shadows, func(i, j int) bool {
+		return shadows[i].Resource.Path() < shadows[j].Resource.Path()
+	})
+	return shadows, nil
 }
 
-// DefaultBundle represents a default search path of:
-//  - The current working directory
-//  - The directory containing the executable
-//  - The package source-code directory
-//  - The executable treated as a ZipBundle
-var DefaultBundle BundleSequence
-
-func init() {
-	var cwd, cur_pkg, exe_dir, exe Bundle
-	cwd = OpenFS(".")
-	cur_pkg = OpenAutoBundle(OpenCurrentPackage)
-
-	if exe_path, err := ExecutablePath(); err == nil {
-		exe_dir = OpenFS(filepath.Dir(exe_path))
-		if exe, err = OpenZip(exe_path); err == nil {
-			DefaultBundle = append(DefaultBundle, exe)
+// An OwningSequence is a BundleSequence which owns its sub-bundles.
+// It searches them exactly like a BundleSequence, but its Close method
+// closes every sub-bundle instead of being a no-op.
+type OwningSequence []Bundle
+
+// Close closes every non-nil sub-bundle, in order. All sub-bundles
+// are closed even if some fail; the errors are combined into a
+// MultiError if there is more than one.
+func (seq OwningSequence) Close() error {
+	var errs []error
+	for _, bundle := range seq {
+		if bundle == nil {
+			continue
+		}
+		if err := bundle.Close(); err != nil {
+			errs = append(errs, err)
 		}
 	}
+	return errorList(errs)
+}
+
+// Bundles implements the Layered interface.
+func (seq OwningSequence) Bundles() []Bundle {
+	return seq
+}
+
+// Open is the same as BundleSequence.Open.
+func (seq OwningSequence) Open(path string) (io.ReadCloser, error) {
+	return BundleSequence(seq).Open(path)
+}
+
+// Find is the same as BundleSequence.Find.
+func (seq OwningSequence) Find(path string) (Resource, error) {
+	return BundleSequence(seq).Find(path)
+}
 
-	DefaultBundle = append(DefaultBundle, cwd, exe_dir, cur_pkg, exe)
+// FindAll is the same as BundleSequence.FindAll.
+func (seq OwningSequence) FindAll(path string) ([]Resource, error) {
+	return BundleSequence(seq).FindAll(path)
 }
 
-// Open() is a shortcut for DefaultBundle.Open()
-func Open(path string) (io.ReadCloser, error) {
-	return DefaultBundle.Open(path)
+// Glob is the same as BundleSequence.Glob.
+func (seq OwningSequence) Glob(pattern string) ([]Resource, error) {
+	return BundleSequence(seq).Glob(pattern)
 }
 
-// Find() is a shortcut for DefaultBundle.Find()
-func Find(path string) (Resource, error) {
-	return DefaultBundle.Find(path)
+// List is the same as BundleSequence.List.
+func (seq OwningSequence) List() ([]Resource, error) {
+	return BundleSequence(seq).List()
 }
 
-// Glob() is a shortcut for DefaultBundle.Glob()
-func Glob(pattern string) ([]Resource, error) {
-	return DefaultBundle.Glob(pattern)
+// All is the same as BundleSequence.All.
+func (seq OwningSequence) All() iter.Seq2[Resource, error] {
+	return BundleSequence(seq).All()
 }
 
-// List() is a shortcut for DefaultBundle.List()
-func List() ([]Resource, error) {
-	return DefaultBundle.List()
+// Shadows is the same as BundleSequence.Shadows.
+func (seq OwningSequence) Shadows() ([]Shadow, error) {
+	return BundleSequence(seq).Shadows()
 }
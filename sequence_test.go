@@ -0,0 +1,177 @@
+package resources
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	. "testing"
+)
+
+var OwningSequence_Is_A_Bundle Bundle = OwningSequence{}
+var OwningSequence_Is_A_Searcher Searcher = OwningSequence{}
+var OwningSequence_Is_A_Lister Lister = OwningSequence{}
+
+// closeBundle is a Bundle which records calls to Close.
+type closeBundle struct {
+	closed bool
+	err    error
+}
+
+func (cb *closeBundle) Open(path string) (io.ReadCloser, error) {
+	return nil, ErrNotFound
+}
+
+func (cb *closeBundle) Close() error {
+	cb.closed = true
+	return cb.err
+}
+
+func TestOwningSequenceClose(t *T) {
+	errA, errB := errors.New("a failed"), errors.New("b failed")
+	a, b, c := &closeBundle{err: errA}, &closeBundle{}, &closeBundle{err: errB}
+
+	err := OwningSequence{a, nil, b, c}.Close()
+	for i, cb := range []*closeBundle{a, b, c} {
+		if !cb.closed {
+			t.Errorf("sub-bundle %d not closed", i)
+		}
+	}
+	if !errors.Is(err, errA) || !errors.Is(err, errB) {
+		t.Errorf("Close() = %v, want both sub-bundle errors", err)
+	}
+	t.Log("Close():", err)
+
+	if err := (OwningSequence{b}).Close(); err != nil {
+		t.Errorf("Close() = %v, want nil", err)
+	}
+}
+
+func TestBundleSequenceListShadows(t *T) {
+	a := zipOfFiles(t, map[string]string{"c": "a", "b": "a"})
+	b := zipOfFiles(t, map[string]string{"b": "b", "a": "b"})
+	c := zipOfFiles(t, map[string]string{"b": "c", "c": "c"})
+	seq := BundleSequence{a, nil, b, c}
+
+	list, err := seq.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, rsrc := range list {
+		got = append(got, rsrc.Path())
+	}
+	if len(got) != 3 || got[0] != "a" || got[1] != "b" || got[2] != "c" {
+		t.Errorf("List() = %v, want [a b c]", got)
+	}
+	if data := readAll(t, seq, "b"); data != "a" {
+		t.Errorf("Open(b) = %q, want a", data)
+	}
+
+	shadows, err := seq.Shadows()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Shadow{{nil, 2, 0}, {nil, 3, 0}, {nil, 3, 0}}
+	wantPaths := []string{"b", "b", "c"}
+	if len(shadows) != len(want) {
+		t.Fatalf("Shadows() = %v, want %d shadows", shadows, len(want))
+	}
+	for i, s := range shadows {
+		if s.Resource.Path() != wantPaths[i] || s.Layer != want[i].Layer || s.By != want[i].By {
+			t.Errorf("Shadows()[%d] = %s %d %d, want %s %d %d", i, s.Resource.Path(), s.Layer, s.By, wantPaths[i], want[i].Layer, want[i].By)
+		}
+	}
+}
+
+func TestSequenceAll(t *T) {
+	a := zipOfFiles(t, map[string]string{"c": "a", "b": "a"})
+	b := zipOfFiles(t, map[string]string{"b": "b", "a": "b"})
+	dir := OpenDir(t.TempDir()).(*dirBundle)
+	c := &listBundle{dir, []Resource{dir.file("d"), dir.file("b"), dir.file("d")}}
+
+	// All has the resources of List, in the same order.
+	for _, s := range []*Sequence{
+		{Layers: BundleSequence{a, nil, b, c}},
+		{Layers: BundleSequence{a, nil, b, c}, Strategy: LastWins},
+	} {
+		list, err := s.List()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var all []Resource
+		for rsrc, err := range s.All() {
+			if err != nil {
+				t.Fatal(err)
+			}
+			all = append(all, rsrc)
+		}
+		if !reflect.DeepEqual(all, list) {
+			t.Errorf("All() = %v, want %v", all, list)
+		}
+	}
+
+	// Iterating can stop early.
+	n := 0
+	for range (BundleSequence{a, b, c}).All() {
+		if n++; n == 2 {
+			break
+		}
+	}
+
+	// Failing layers are the only thing yielded.
+	for rsrc, err := range (BundleSequence{a, brokenBundle{}}).All() {
+		if rsrc != nil || err != errBroken {
+			t.Errorf("All() of a broken layer yielded %v, %v", rsrc, err)
+		}
+	}
+}
+
+// BenchmarkBundleSequenceList merges the listings of layers of 50000
+// resources each, half of them shadowed by the layer before.
+func BenchmarkBundleSequenceList(b *B) {
+	const n = 50000
+	dir := &dirBundle{&fsBundle{base: "/"}}
+	var seq BundleSequence
+	for layer := 0; layer < 3; layer++ {
+		list := make([]Resource, n)
+		for i := range list {
+			list[i] = dir.file(fmt.Sprintf("dir%d/file%d", i%100, i+layer*n/2))
+		}
+		seq = append(seq, &listBundle{dir, list})
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if list, err := seq.List(); err != nil || len(list) != 2*n {
+			b.Fatalf("List() = %d resources, %v", len(list), err)
+		}
+	}
+}
+
+// BenchmarkBundleSequenceAll merges the same listings as
+// BenchmarkBundleSequenceList as they are iterated over.
+func BenchmarkBundleSequenceAll(b *B) {
+	const n = 50000
+	dir := &dirBundle{&fsBundle{base: "/"}}
+	var seq BundleSequence
+	for layer := 0; layer < 3; layer++ {
+		list := make([]Resource, n)
+		for i := range list {
+			list[i] = dir.file(fmt.Sprintf("dir%d/file%d", i%100, i+layer*n/2))
+		}
+		seq = append(seq, &listBundle{dir, list})
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		count := 0
+		for _, err := range seq.All() {
+			if err != nil {
+				b.Fatal(err)
+			}
+			count++
+		}
+		if count != 2*n {
+			b.Fatalf("All() = %d resources, want %d", count, 2*n)
+		}
+	}
+}
@@ -0,0 +1,707 @@
+package resources
+
+import (
+	"container/heap"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// A Candidate is a resource found at a path in one layer of a
+// Sequence.
+type Candidate struct {
+	Layer    int    // index of the layer in the Sequence
+	Bundle   Bundle // the layer
+	Resource Resource
+}
+
+// A Strategy chooses which of the candidates for a path is used,
+// returning its index in candidates. The candidates are in layer
+// order, and there is at least one.
+//
+// Find and Glob choose between the layers implementing Searcher, and
+// List between those implementing Lister, so they agree on the
+// resource of each path. Open also has as candidates the layers which
+// can't find the path because they, or the bundles they wrap, don't
+// implement Searcher, if opening the path in them succeeds, but the
+// Stat of their resources fails.
+type Strategy func(path string, candidates []Candidate) (int, error)
+
+// FirstWins uses the resource of the first layer which has one, as a
+// BundleSequence does. It is the Strategy of a Sequence whose Strategy
+// is nil, which avoids searching every layer.
+func FirstWins(path string, candidates []Candidate) (int, error) {
+	return 0, nil
+}
+
+// LastWins uses the resource of the last layer which has one.
+func LastWins(path string, candidates []Candidate) (int, error) {
+	return len(candidates) - 1, nil
+}
+
+// NewestWins uses the most recently modified resource, or the first of
+// those modified at the same time. Resources which can't be Stat'ed
+// are the oldest.
+func NewestWins(path string, candidates []Candidate) (int, error) {
+	best, best_info := 0, os.FileInfo(nil)
+	for i, c := range candidates {
+		info, err := c.Resource.Stat()
+		if err != nil {
+			continue
+		}
+		if best_info == nil || info.ModTime().After(best_info.ModTime()) {
+			best, best_info = i, info
+		}
+	}
+	return best, nil
+}
+
+// compareVersions compares dotted version numbers, eg: "1.10.2" and
+// "v1.9", numerically part by part, returning -1, 0 or 1. Missing
+// parts are zero, and parts which aren't numbers are compared as
+// strings.
+func compareVersions(a, b string) int {
+	as := strings.Split(strings.TrimPrefix(a, "v"), ".")
+	bs := strings.Split(strings.TrimPrefix(b, "v"), ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		ap, bp := "0", "0"
+		if i < len(as) {
+			ap = as[i]
+		}
+		if i < len(bs) {
+			bp = bs[i]
+		}
+		an, aerr := strconv.Atoi(ap)
+		bn, berr := strconv.Atoi(bp)
+		switch {
+		case aerr == nil && berr == nil && an != bn:
+			if an < bn {
+				return -1
+			}
+			return 1
+		case (aerr != nil || berr != nil) && ap != bp:
+			if ap < bp {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// VersionWins returns a Strategy using the resource of the layer with
+// the largest version, as given by the "version" field of the JSON
+// manifest at manifest_path in each layer, eg:
+//
+//	{"version": "1.4.2"}
+//
+// Layers without a manifest have the smallest version, and the first
+// of the layers with the same version is used. The versions of layers
+// of comparable types are kept while their manifests are unchanged, as
+// by a LoadCache; other manifests are read every time.
+func VersionWins(manifest_path string) Strategy {
+	type entry struct {
+		info    os.FileInfo
+		version string
+	}
+	var cache sync.Map // Bundle -> entry
+	version := func(b Bundle) (string, error) {
+		var info os.FileInfo
+		searcher, ok := b.(Searcher)
+		if ok && reflect.TypeOf(b).Comparable() {
+			rsrc, err := searcher.Find(manifest_path)
+			if err == ErrNotFound {
+				cache.Delete(b)
+				return "", nil
+			} else if err != nil {
+				return "", err
+			}
+			if info, err = rsrc.Stat(); err != nil {
+				return "", err
+			}
+			if e, ok := cache.Load(b); ok && sameContents(e.(entry).info, info) {
+				return e.(entry).version, nil
+			}
+		}
+
+		var manifest struct{ Version string }
+		data, err := readResource(b, manifest_path)
+		if err == nil {
+			err = json.Unmarshal(data, &manifest)
+		} else if err == ErrNotFound {
+			err = nil
+		}
+		if err != nil {
+			return "", err
+		}
+		if info != nil && identifies(info) {
+			cache.Store(b, entry{info, manifest.Version})
+		}
+		return manifest.Version, nil
+	}
+
+	return func(path string, candidates []Candidate) (int, error) {
+		best, best_version := 0, ""
+		for i, c := range candidates {
+			v, err := version(c.Bundle)
+			if err != nil {
+				return 0, err
+			}
+			if i == 0 || v != "" && (best_version == "" || compareVersions(v, best_version) > 0) {
+				best, best_version = i, v
+			}
+		}
+		return best, nil
+	}
+}
+
+// errNoStat is returned by the Stat of candidates found in layers
+// which don't implement Searcher.
+var errNoStat = errors.New("resources: layer can't stat resources")
+
+// openedResource is a candidate found by opening it in a layer which
+// doesn't implement Searcher. The reader opened to find it is kept for
+// the first Open, so the resource is only fetched once if it is used.
+type openedResource struct {
+	bundle Bundle
+	path   string
+
+	mu  sync.Mutex
+	rdr io.ReadCloser // until taken by Open, or released
+}
+
+// take returns the reader opened to find the resource, or nil if it
+// has been taken or released.
+func (or *openedResource) take() io.ReadCloser {
+	or.mu.Lock()
+	defer or.mu.Unlock()
+	rdr := or.rdr
+	or.rdr = nil
+	return rdr
+}
+
+// release closes the reader opened to find the resource, unless it
+// has been taken.
+func (or *openedResource) release() {
+	if rdr := or.take(); rdr != nil {
+		rdr.Close()
+	}
+}
+
+func (or *openedResource) Path() string {
+	return or.path
+}
+
+func (or *openedResource) String() string {
+	return or.path
+}
+
+func (or *openedResource) Stat() (os.FileInfo, error) {
+	return nil, errNoStat
+}
+
+func (or *openedResource) Open() (io.ReadCloser, error) {
+	if rdr := or.take(); rdr != nil {
+		return rdr, nil
+	}
+	return or.bundle.Open(or.path)
+}
+
+// release releases the readers of the candidates found by opening
+// them, except that of keep.
+func release(candidates []Candidate, keep Resource) {
+	for _, c := range candidates {
+		if or, ok := c.Resource.(*openedResource); ok && c.Resource != keep {
+			or.release()
+		}
+	}
+}
+
+// A Sequence is a meta-bundle, like a BundleSequence, with a Strategy
+// choosing which of its layers' resources is used when several have
+// one at the same path, and an ErrorPolicy deciding what happens when
+// layers fail. It uses them for Open, Find, Glob and List alike. A
+// BundleSequence searches its sub-bundles with a Sequence of them
+// with the default settings.
+//
+// Like a BundleSequence, nil layers are skipped, and Close is a no-op.
+type Sequence struct {
+	Layers BundleSequence
+
+	// Strategy chooses between the resources of the layers. If nil,
+	// the first layer's resource is used, as by a BundleSequence,
+	// without searching the later layers.
+	Strategy Strategy
+
+	// Errors is what to do when a layer fails with an error other
+	// than ErrNotFound. FailFast returns the error unchanged; the
+	// errors returned by FailIfNoneSucceed are *LayerError.
+	Errors ErrorPolicy
+
+	// OnError, if not nil, is called with every error of a layer,
+	// whatever the ErrorPolicy. It is called from one goroutine at a
+	// time, in layer order.
+	OnError func(*LayerError)
+
+	// Parallel is how many layers may be searched at once, which
+	// hides the latency of layers such as HTTP bundles. The results
+	// are still used in layer order, and as soon as they are known no
+	// more layers are searched. The Opens of the later layers still
+	// running are then cancelled, if they implement ContextOpener;
+	// other searches run to completion, and their results are
+	// discarded, closing any readers they opened. If 0 or 1, layers
+	// are searched one at a time.
+	Parallel int
+
+	// Lookups, if not nil, remembers the layers paths are found in by
+	// Open and Find, if there is no Strategy, or that they aren't in
+	// any. See LookupCache for when its entries are invalidated. While
+	// it is set, the Layers must not be modified in place: assigning
+	// another slice to Layers makes it forget everything instead.
+	Lookups *LookupCache
+}
+
+// NewSequence returns a Sequence of the layers using the Strategy.
+func NewSequence(strategy Strategy, layers ...Bundle) *Sequence {
+	return &Sequence{Layers: layers, Strategy: strategy}
+}
+
+// Bundles implements the Layered interface.
+func (s *Sequence) Bundles() []Bundle {
+	return s.Layers
+}
+
+func (s *Sequence) Close() error {
+	return nil
+}
+
+func (s *Sequence) strategy() Strategy {
+	if s.Strategy == nil {
+		return FirstWins
+	}
+	return s.Strategy
+}
+
+// findIn finds the resource at path in a layer, for op. Resources which
+// a layer can open but not find, because it or a bundle it wraps isn't
+// a Searcher, are only found for Open, by opening them.
+func findIn(ctx context.Context, op Op, bundle Bundle, path string) (Resource, error) {
+	if searcher, ok := bundle.(Searcher); ok {
+		rsrc, err := searcher.Find(path)
+		if err != ErrNotFound || op != OpOpen || searchable(bundle) {
+			return rsrc, err
+		}
+	} else if op != OpOpen {
+		return nil, ErrNotFound
+	}
+	rdr, err := openContext(ctx, bundle, path)
+	if err != nil {
+		return nil, err
+	}
+	return &openedResource{bundle: bundle, path: path, rdr: rdr}, nil
+}
+
+// cached returns the layer of the Lookups entry for path and op, if
+// there is one and the layer is still there, or -1 if path is in none
+// of them, and the generation to store the result of searching for it
+// with. Layers are only used if there is no Strategy, as for storing
+// them.
+func (s *Sequence) cached(op Op, path string) (layer int, gen uint64, ok bool) {
+	s.Lookups.watch(s.Layers)
+	layer, gen, ok = s.Lookups.lookup(op, path, s.Layers)
+	if ok && layer >= 0 && (s.Strategy != nil || layer >= len(s.Layers) || s.Layers[layer] == nil) {
+		return 0, gen, false
+	}
+	return layer, gen, ok
+}
+
+// remember stores the layer path was found in by op, or -1 if it
+// wasn't found, in Lookups, unless a layer failed, or a Strategy chose
+// the layer.
+func (s *Sequence) remember(op Op, path string, layer int, err error, errs []error, gen uint64) {
+	switch {
+	case err == nil && s.Strategy == nil:
+		s.Lookups.store(op, path, layer, gen)
+	case err == ErrNotFound && len(errs) == 0:
+		s.Lookups.store(op, path, -1, gen)
+	}
+}
+
+// candidates finds the resource at path for op in every layer, or only
+// the first which has it if there is no Strategy. The errors of layers
+// skipped by the ErrorPolicy are returned too.
+func (s *Sequence) candidates(op Op, path string) ([]Candidate, []error, error) {
+	var found []Candidate
+	var errs []error
+	find := func(ctx context.Context, bundle Bundle) (Resource, error) {
+		return findIn(ctx, op, bundle, path)
+	}
+	err := eachLayer(s, find, func(i int, bundle Bundle, rsrc Resource, err error) (bool, error) {
+		if err == nil {
+			found = append(found, Candidate{i, bundle, rsrc})
+			return s.Strategy == nil, nil
+		} else if err != ErrNotFound {
+			return false, s.layerFailed(&errs, i, bundle, op, path, err)
+		}
+		return false, nil
+	}, func(rsrc Resource) {
+		release([]Candidate{{Resource: rsrc}}, nil)
+	})
+	if err != nil {
+		release(found, nil)
+		return nil, nil, err
+	}
+	return found, errs, nil
+}
+
+// choose applies the Strategy to the candidates for path.
+func (s *Sequence) choose(path string, candidates []Candidate, errs []error) (Candidate, error) {
+	if len(candidates) == 0 {
+		return Candidate{}, s.missing(errs)
+	}
+	i, err := s.strategy()(path, candidates)
+	if err != nil {
+		return Candidate{}, err
+	}
+	if i < 0 || i >= len(candidates) {
+		return Candidate{}, fmt.Errorf("resources: strategy chose candidate %d of %d for %s", i, len(candidates), path)
+	}
+	return candidates[i], nil
+}
+
+// find finds the resource at path for op in the layers, and chooses
+// one with the Strategy. The readers of the other candidates found by
+// opening them are released.
+func (s *Sequence) find(op Op, path string, gen uint64) (Candidate, error) {
+	candidates, errs, err := s.candidates(op, path)
+	if err != nil {
+		return Candidate{}, err
+	}
+	c, err := s.choose(path, candidates, errs)
+	release(candidates, c.Resource)
+	s.remember(op, path, c.Layer, err, errs, gen)
+	return c, err
+}
+
+func (s *Sequence) Open(path string) (io.ReadCloser, error) {
+	layer, gen, ok := s.cached(OpOpen, path)
+	if ok && layer < 0 {
+		return nil, ErrNotFound
+	} else if ok {
+		if rdr, err := s.Layers[layer].Open(path); err == nil {
+			return rdr, nil
+		}
+		s.Lookups.Invalidate(path)
+		_, gen, _ = s.cached(OpOpen, path)
+	}
+
+	if s.Strategy != nil {
+		c, err := s.find(OpOpen, path, gen)
+		if err != nil {
+			return nil, err
+		}
+		return c.Resource.Open()
+	}
+
+	var rdr io.ReadCloser
+	var errs []error
+	open := func(ctx context.Context, bundle Bundle) (io.ReadCloser, error) {
+		return openContext(ctx, bundle, path)
+	}
+	err := eachLayer(s, open, func(i int, bundle Bundle, r io.ReadCloser, err error) (bool, error) {
+		if err == nil {
+			rdr, layer = r, i
+			return true, nil
+		} else if err != ErrNotFound {
+			return false, s.layerFailed(&errs, i, bundle, OpOpen, path, err)
+		}
+		return false, nil
+	}, func(r io.ReadCloser) { r.Close() })
+	if err != nil {
+		return nil, err
+	} else if rdr != nil {
+		s.remember(OpOpen, path, layer, nil, errs, gen)
+		return rdr, nil
+	}
+	err = s.missing(errs)
+	s.remember(OpOpen, path, -1, err, errs, gen)
+	return nil, err
+}
+
+func (s *Sequence) Find(path string) (Resource, error) {
+	layer, gen, ok := s.cached(OpFind, path)
+	if ok && layer < 0 {
+		return nil, ErrNotFound
+	} else if ok {
+		if rsrc, err := findIn(context.Background(), OpFind, s.Layers[layer], path); err == nil {
+			return rsrc, nil
+		}
+		s.Lookups.Invalidate(path)
+		_, gen, _ = s.cached(OpFind, path)
+	}
+
+	c, err := s.find(OpFind, path, gen)
+	if err != nil {
+		return nil, err
+	}
+	return c.Resource, nil
+}
+
+// FindAll finds the resource at path in every layer implementing
+// Searcher which has one, in layer order regardless of the Strategy,
+// following the ErrorPolicy.
+func (s *Sequence) FindAll(path string) ([]Resource, error) {
+	var found []Resource
+	var errs []error
+	find := func(_ context.Context, bundle Bundle) (Resource, error) {
+		if searcher, ok := bundle.(Searcher); ok {
+			return searcher.Find(path)
+		}
+		return nil, ErrNotFound
+	}
+	err := eachLayer(s, find, func(i int, bundle Bundle, rsrc Resource, err error) (bool, error) {
+		if err == nil {
+			found = append(found, rsrc)
+		} else if err != ErrNotFound {
+			return false, s.layerFailed(&errs, i, bundle, OpFind, path, err)
+		}
+		return false, nil
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(found) == 0 {
+		return nil, s.missing(errs)
+	}
+	return found, nil
+}
+
+// resolve applies the Strategy to resources found in the layers,
+// grouped by path, sorted by path as by a BundleSequence.
+func (s *Sequence) resolve(found []Candidate) ([]Resource, error) {
+	groups := make(map[string][]Candidate)
+	var order []string
+	for _, c := range found {
+		p := c.Resource.Path()
+		if _, ok := groups[p]; !ok {
+			order = append(order, p)
+		}
+		groups[p] = append(groups[p], c)
+	}
+	sort.Strings(order)
+	rsrcs := make([]Resource, len(order))
+	for i, p := range order {
+		c, err := s.choose(p, groups[p], nil)
+		if err != nil {
+			return nil, err
+		}
+		rsrcs[i] = c.Resource
+	}
+	return rsrcs, nil
+}
+
+// A layerListing is the resources fn returned for a layer in listings.
+type layerListing struct {
+	layer  int
+	bundle Bundle
+	rsrcs  []Resource
+}
+
+// listings gathers the resources returned by fn for each layer it
+// applies to, in layer order, following the ErrorPolicy.
+func (s *Sequence) listings(op Op, pattern string, fn func(Bundle) ([]Resource, bool, error)) ([]layerListing, error) {
+	type listing struct {
+		rsrcs []Resource
+		ok    bool
+	}
+	var found []layerListing
+	var errs []error
+	succeeded := false
+	list := func(_ context.Context, bundle Bundle) (listing, error) {
+		rsrcs, ok, err := fn(bundle)
+		return listing{rsrcs, ok}, err
+	}
+	err := eachLayer(s, list, func(i int, bundle Bundle, l listing, err error) (bool, error) {
+		if !l.ok {
+			return false, nil
+		}
+		if err != nil && err != ErrNotFound {
+			return false, s.layerFailed(&errs, i, bundle, op, pattern, err)
+		}
+		succeeded = true
+		found = append(found, layerListing{i, bundle, l.rsrcs})
+		return false, nil
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+	if !succeeded && s.Errors == FailIfNoneSucceed && len(errs) > 0 {
+		return nil, errorList(errs)
+	}
+	return found, nil
+}
+
+// collect gathers the resources returned by fn for each layer it
+// applies to, as candidates for the Strategy, or merging them as a
+// BundleSequence does if there is none.
+func (s *Sequence) collect(op Op, pattern string, fn func(Bundle) ([]Resource, bool, error)) ([]Resource, error) {
+	listings, err := s.listings(op, pattern, fn)
+	if err != nil {
+		return nil, err
+	}
+	if s.Strategy == nil {
+		rm := newResourceMerge()
+		for _, l := range listings {
+			rm.add(l.layer, l.rsrcs)
+		}
+		return rm.sorted(), nil
+	}
+	var found []Candidate
+	for _, l := range listings {
+		for _, rsrc := range l.rsrcs {
+			found = append(found, Candidate{l.layer, l.bundle, rsrc})
+		}
+	}
+	return s.resolve(found)
+}
+
+// Glob returns the resources matching pattern in the layers
+// implementing Searcher, sorted by path, applying the Strategy to those
+// with the same path. Like List, the whole result is built in memory.
+func (s *Sequence) Glob(pattern string) ([]Resource, error) {
+	return s.collect(OpGlob, pattern, func(b Bundle) ([]Resource, bool, error) {
+		searcher, ok := b.(Searcher)
+		if !ok {
+			return nil, false, nil
+		}
+		rsrcs, err := searcher.Glob(pattern)
+		return rsrcs, true, err
+	})
+}
+
+// listLayer returns the resources of a layer for List, or false if it
+// isn't a Lister.
+func listLayer(b Bundle) ([]Resource, bool, error) {
+	lister, ok := b.(Lister)
+	if !ok {
+		return nil, false, nil
+	}
+	rsrcs, err := lister.List()
+	return rsrcs, true, err
+}
+
+// List returns the resources of the layers implementing Lister, sorted
+// by path, applying the Strategy to those with the same path. The whole
+// listing is built in memory; All merges it as it is iterated over.
+func (s *Sequence) List() ([]Resource, error) {
+	return s.collect(OpList, "", listLayer)
+}
+
+// All returns an iterator over the resources List returns, in the same
+// order. The listings of the layers are sorted, then merged as they are
+// iterated over, so the merged listing is never built, and iterating
+// can stop early. If listing the layers fails, the error is the only
+// thing yielded.
+func (s *Sequence) All() iter.Seq2[Resource, error] {
+	return func(yield func(Resource, error) bool) {
+		listings, err := s.listings(OpList, "", listLayer)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		lm := make(listingMerge, 0, len(listings))
+		for _, l := range listings {
+			if len(l.rsrcs) > 0 {
+				lm = append(lm, &listingCursor{l, sortedByPath(l.rsrcs)})
+			}
+		}
+		heap.Init(&lm)
+
+		var group []Candidate
+		flush := func() bool {
+			if len(group) == 0 {
+				return true
+			}
+			c, err := s.choose(group[0].Resource.Path(), group, nil)
+			group = group[:0]
+			if err != nil {
+				yield(nil, err)
+				return false
+			}
+			return yield(c.Resource, nil)
+		}
+		for len(lm) > 0 {
+			cur := lm[0]
+			rsrc := cur.rsrcs[0]
+			if len(group) > 0 && group[0].Resource.Path() != rsrc.Path() && !flush() {
+				return
+			}
+			// Only the first resource with a path in each layer is
+			// a candidate.
+			if len(group) == 0 || group[len(group)-1].Layer != cur.layer {
+				group = append(group, Candidate{cur.layer, cur.bundle, rsrc})
+			}
+			if cur.rsrcs = cur.rsrcs[1:]; len(cur.rsrcs) == 0 {
+				heap.Pop(&lm)
+			} else {
+				heap.Fix(&lm, 0)
+			}
+		}
+		flush()
+	}
+}
+
+// sortedByPath returns rsrcs sorted by path, stably, copying them if
+// they aren't already.
+func sortedByPath(rsrcs []Resource) []Resource {
+	less := func(i, j int) bool {
+		return rsrcs[i].Path() < rsrcs[j].Path()
+	}
+	if sort.SliceIsSorted(rsrcs, less) {
+		return rsrcs
+	}
+	rsrcs = append([]Resource(nil), rsrcs...)
+	sort.SliceStable(rsrcs, less)
+	return rsrcs
+}
+
+// A listingCursor is the resources of a layer not yet merged by All.
+type listingCursor struct {
+	layerListing
+	rsrcs []Resource // sorted by path
+}
+
+// A listingMerge is a heap of the cursors of the layers, ordered by
+// their next path, then by layer.
+type listingMerge []*listingCursor
+
+func (lm listingMerge) Len() int      { return len(lm) }
+func (lm listingMerge) Swap(i, j int) { lm[i], lm[j] = lm[j], lm[i] }
+
+func (lm listingMerge) Less(i, j int) bool {
+	pi, pj := lm[i].rsrcs[0].Path(), lm[j].rsrcs[0].Path()
+	if pi != pj {
+		return pi < pj
+	}
+	return lm[i].layer < lm[j].layer
+}
+
+func (lm *listingMerge) Push(x any) {
+	*lm = append(*lm, x.(*listingCursor))
+}
+
+func (lm *listingMerge) Pop() any {
+	old := *lm
+	cur := old[len(old)-1]
+	*lm = old[:len(old)-1]
+	return cur
+}
@@ -0,0 +1,217 @@
+package resources
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	. "testing"
+	"time"
+)
+
+var Sequence_Is_A_Bundle Bundle = &Sequence{}
+var Sequence_Is_A_Searcher Searcher = &Sequence{}
+var Sequence_Is_A_Lister Lister = &Sequence{}
+
+func TestCompareVersions(t *T) {
+	for _, test := range []struct {
+		a, b string
+		want int
+	}{
+		{"1.10.0", "1.9.9", 1},
+		{"v1.2", "1.2.0", 0},
+		{"1.2", "1.2.1", -1},
+		{"2.0-beta", "2.0-alpha", 1},
+	} {
+		if got := compareVersions(test.a, test.b); got != test.want {
+			t.Errorf("compareVersions(%s, %s) = %d, want %d", test.a, test.b, got, test.want)
+		}
+	}
+}
+
+// dirWith returns a directory bundle of the given files, all modified
+// at modtime.
+func dirWith(t *T, modtime time.Time, files map[string]string) Bundle {
+	dir := t.TempDir()
+	for name, data := range files {
+		file := filepath.Join(dir, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(file), 0755)
+		if err := os.WriteFile(file, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		os.Chtimes(file, modtime, modtime)
+	}
+	return OpenDir(dir)
+}
+
+func TestSequenceStrategies(t *T) {
+	now := time.Now()
+	a := dirWith(t, now.Add(-2*time.Hour), map[string]string{
+		"x": "a", "only-a": "a", "version.json": `{"version": "1.2.0"}`,
+	})
+	b := dirWith(t, now, map[string]string{
+		"x": "b", "y": "b", "version.json": `{"version": "1.10.0"}`,
+	})
+	c := zipOfFiles(t, map[string]string{"x": "c", "y": "c"})
+	opener := &closeBundle{} // finds nothing, and isn't a Searcher
+	layers := []Bundle{a, nil, opener, b, c}
+
+	for _, test := range []struct {
+		name     string
+		strategy Strategy
+		x, y     string
+	}{
+		{"nil", nil, "a", "b"},
+		{"FirstWins", FirstWins, "a", "b"},
+		{"LastWins", LastWins, "c", "c"},
+		{"NewestWins", NewestWins, "b", "b"},
+		{"VersionWins", VersionWins("version.json"), "b", "b"},
+		{"custom", func(path string, candidates []Candidate) (int, error) {
+			for i, c := range candidates {
+				if c.Layer == 4 {
+					return i, nil
+				}
+			}
+			return 0, nil
+		}, "c", "c"},
+	} {
+		seq := NewSequence(test.strategy, layers...)
+		if got := readAll(t, seq, "x"); got != test.x {
+			t.Errorf("%s: Open(x) = %q, want %q", test.name, got, test.x)
+		}
+		rsrc, err := seq.Find("y")
+		if err != nil {
+			t.Fatal(err)
+		}
+		rdr, _ := rsrc.Open()
+		data := make([]byte, 1)
+		rdr.Read(data)
+		rdr.Close()
+		if string(data) != test.y {
+			t.Errorf("%s: Find(y) = %q, want %q", test.name, data, test.y)
+		}
+
+		list, err := seq.List()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, rsrc := range list {
+			if rsrc.Path() != "x" {
+				continue
+			}
+			rdr, _ := rsrc.Open()
+			rdr.Read(data)
+			rdr.Close()
+			if string(data) != test.x {
+				t.Errorf("%s: List() has x from %q, want %q", test.name, data, test.x)
+			}
+		}
+		if len(list) != 4 {
+			t.Errorf("%s: List() = %v, want 4 resources", test.name, list)
+		}
+		if matches, err := seq.Glob("only-*"); err != nil || len(matches) != 1 {
+			t.Errorf("%s: Glob(only-*) = %v, %v", test.name, matches, err)
+		}
+	}
+
+	bad := NewSequence(func(string, []Candidate) (int, error) { return 5, nil }, a)
+	if _, err := bad.Open("x"); err == nil {
+		t.Error("Open with an out of range choice succeeded")
+	}
+}
+
+func TestSequenceUnsearchableLayers(t *T) {
+	dir := dirWith(t, time.Now(), map[string]string{"x": "dir", "y": "dir"})
+	cb := &countBundle{contents: map[string]string{"x": "cb", "z": "cb"}}
+	seq := NewSequence(LastWins, dir, cb)
+
+	// Open finds x in cb by opening it, and only opens it once.
+	if got := readAll(t, seq, "x"); got != "cb" || cb.opens != 1 {
+		t.Errorf("Open(x) = %q with %d opens, want cb with 1", got, cb.opens)
+	}
+	if got := readAll(t, seq, "z"); got != "cb" {
+		t.Errorf("Open(z) = %q, want cb", got)
+	}
+
+	// Find and Glob only choose between Searchers, so they agree.
+	rsrc, err := seq.Find("x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	matches, err := seq.Glob("x")
+	if err != nil || len(matches) != 1 || !reflect.DeepEqual(matches[0], rsrc) {
+		t.Errorf("Glob(x) = %v, %v, want %v", matches, err, rsrc)
+	}
+	if _, err := seq.Find("z"); err != ErrNotFound {
+		t.Errorf("Find(z) = %v, want ErrNotFound", err)
+	}
+}
+
+func TestSequenceWrappedUnsearchableLayers(t *T) {
+	base := zipOf(t, "x", "base")
+	plain := &countBundle{contents: map[string]string{"x": "plain"}}
+	layers := map[string]Bundle{
+		"Watched":      Watched(plain, &funcWatcher{}),
+		"OpenFiltered": OpenFiltered(plain, nil),
+		"Instrument":   Instrument(&countObserver{})(OpenFiltered(plain, nil)),
+	}
+	for name, layer := range layers {
+		if got := readAll(t, NewSequence(FirstWins, layer, base), "x"); got != "plain" {
+			t.Errorf("Open(x) with %s first and FirstWins = %q, want plain", name, got)
+		}
+		if got := readAll(t, NewSequence(LastWins, base, layer), "x"); got != "plain" {
+			t.Errorf("Open(x) with %s last and LastWins = %q, want plain", name, got)
+		}
+		if _, err := NewSequence(LastWins, base, layer).Find("x"); err != nil {
+			t.Errorf("Find(x) with %s last = %v, want base's", name, err)
+		}
+	}
+
+	sp := NewSearchPath(base)
+	sp.Configure(func(s *Sequence) { s.Strategy = FirstWins })
+	restore := sp.WithOverride(plain)
+	if got := readAll(t, sp, "x"); got != "plain" {
+		t.Errorf("Open(x) with an override = %q, want plain", got)
+	}
+	restore()
+	if got := readAll(t, sp, "x"); got != "base" {
+		t.Errorf("Open(x) after restore = %q, want base", got)
+	}
+}
+
+func TestVersionWinsChanges(t *T) {
+	old := time.Now().Add(-time.Hour)
+	a := dirWith(t, old, map[string]string{"x": "a", "version.json": `{"version": "2"}`})
+	b := dirWith(t, old, map[string]string{"x": "b", "version.json": `{"version": "1"}`})
+	seq := NewSequence(VersionWins("version.json"), a, b)
+	if got := readAll(t, seq, "x"); got != "a" {
+		t.Fatalf("Open(x) = %q, want a", got)
+	}
+
+	manifest := filepath.Join(b.(*dirBundle).base, "version.json")
+	if err := os.WriteFile(manifest, []byte(`{"version": "3"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got := readAll(t, seq, "x"); got != "b" {
+		t.Errorf("Open(x) after a new version = %q, want b", got)
+	}
+}
+
+func TestSearchPathConfigure(t *T) {
+	a := zipOf(t, "x", "a")
+	b := zipOf(t, "x", "b")
+	sp := NewSearchPath(a, b)
+	if got := readAll(t, sp, "x"); got != "a" {
+		t.Errorf("Open(x) = %q, want a", got)
+	}
+	sp.Configure(func(s *Sequence) {
+		s.Strategy = LastWins
+		s.Layers = nil
+	})
+	if got := readAll(t, sp, "x"); got != "b" {
+		t.Errorf("Open(x) with LastWins = %q, want b", got)
+	}
+	sp.Prepend(zipOf(t, "x", "c"))
+	if got := readAll(t, sp, "x"); got != "b" {
+		t.Errorf("Open(x) after Prepend = %q, want b", got)
+	}
+}
@@ -0,0 +1,70 @@
+// Package texttemplate parses text/template templates from the
+// resources of bundles.
+package texttemplate
+
+import (
+	"text/template"
+
+	"gopkg.in/cookieo9/resources-go.v2"
+	"gopkg.in/cookieo9/resources-go.v2/internal/tmpl"
+)
+
+// ParseTemplates parses the templates matching any of the patterns in
+// s into a new template called name, as template.New(name).ParseGlob
+// does with files. Each template is named by the base name of its
+// resource, so name should be that of the template to execute by
+// default.
+//
+// If s is resources.Layered, eg: a BundleSequence or SearchPath,
+// templates in each layer override those with the same name in later
+// layers, eg: so a theme can replace some of the default templates,
+// whatever the Strategy of a Sequence.
+func ParseTemplates(name string, s resources.Searcher, patterns ...string) (*template.Template, error) {
+	return Parse(template.New(name), s, patterns...)
+}
+
+// Parse is like ParseTemplates, but parses the templates into t, as
+// t.ParseGlob does.
+func Parse(t *template.Template, s resources.Searcher, patterns ...string) (*template.Template, error) {
+	return tmpl.Parse(t, s, patterns)
+}
+
+// A Reloader parses templates again whenever a Watcher reports a
+// change to them.
+type Reloader struct {
+	r *tmpl.Reloader[*template.Template]
+}
+
+// NewReloader parses the templates matching the patterns in s into a
+// clone of base, eg: template.New(name) with functions added, and
+// parses them into a new clone when w reports a change to a path
+// matching one of the patterns.
+func NewReloader(base *template.Template, s resources.Searcher, w resources.Watcher, patterns ...string) (*Reloader, error) {
+	r, err := tmpl.NewTemplateReloader(base, s, w, patterns)
+	if err != nil {
+		return nil, err
+	}
+	return &Reloader{r}, nil
+}
+
+// Template returns the latest templates parsed. If parsing them again
+// fails, the previous templates are kept, and Err returns the error.
+func (r *Reloader) Template() *template.Template {
+	return r.r.Value()
+}
+
+// Reload parses the templates again now.
+func (r *Reloader) Reload() error {
+	return r.r.Reload()
+}
+
+// Err returns the error of the last attempt to parse the templates, if
+// it failed.
+func (r *Reloader) Err() error {
+	return r.r.Err()
+}
+
+// Close stops watching for changes.
+func (r *Reloader) Close() {
+	r.r.Close()
+}
@@ -0,0 +1,95 @@
+package texttemplate
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	. "testing"
+	"text/template"
+
+	"gopkg.in/cookieo9/resources-go.v2"
+)
+
+// dirOf returns a directory bundle of the given files.
+func dirOf(t *T, files map[string]string) resources.Bundle {
+	dir := t.TempDir()
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return resources.OpenDir(dir)
+}
+
+func execute(t *T, tmpl *template.Template) string {
+	buf := new(bytes.Buffer)
+	if err := tmpl.Execute(buf, "<x>"); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestParseTemplates(t *T) {
+	theme := dirOf(t, map[string]string{
+		"header.txt": `themed {{.}}`,
+	})
+	defaults := dirOf(t, map[string]string{
+		"page.txt":   `{{template "header.txt" .}} page`,
+		"header.txt": `{{.}}`,
+	})
+
+	// The layers of a Sequence, even behind a middleware, override
+	// each other.
+	seq := resources.Chain(resources.NewSequence(nil, theme, defaults), resources.Tracing())
+	tmpl, err := ParseTemplates("page.txt", seq.(resources.Searcher), "*.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tmpl.Name() != "page.txt" {
+		t.Errorf("root template = %q, want page.txt", tmpl.Name())
+	}
+	if got, want := execute(t, tmpl), "themed <x> page"; got != want {
+		t.Errorf("page.txt = %q, want %q", got, want)
+	}
+}
+
+// testWatcher reports the changes it's told to.
+type testWatcher struct {
+	fn func(string)
+}
+
+func (tw *testWatcher) Watch(fn func(string)) func() {
+	tw.fn = fn
+	return func() { tw.fn = nil }
+}
+
+func TestReloader(t *T) {
+	dir := t.TempDir()
+	write := func(data string) {
+		if err := os.WriteFile(filepath.Join(dir, "page.txt"), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(`{{upper .}}`)
+
+	base := template.New("page.txt").Funcs(template.FuncMap{"upper": strings.ToUpper})
+	w := new(testWatcher)
+	r, err := NewReloader(base, resources.OpenDir(dir).(resources.Searcher), w, "*.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if got := execute(t, r.Template()); got != "<X>" {
+		t.Errorf("page.txt = %q", got)
+	}
+
+	write(`{{`)
+	w.fn("page.txt")
+	if r.Err() == nil {
+		t.Error("Err() = nil after a broken template")
+	}
+	if got := execute(t, r.Template()); got != "<X>" {
+		t.Errorf("page.txt after broken change = %q", got)
+	}
+}
@@ -0,0 +1,379 @@
+package resources
+
+import (
+	"io"
+	"path"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// VariantOptions describe which variants of resources a Variants
+// bundle prefers.
+//
+// A variant of a resource has the same path with a scale, and
+// tags, added before its extension, as in
+//
+//	stem[@<scale>x][.<tag>]...ext
+//
+// eg: "icon@2x.png", "shader.linux.glsl" and "music.low.ogg" are
+// variants of "icon.png", "shader.glsl" and "music.ogg". The resource
+// itself is the variant with no tags and a scale of 1.
+type VariantOptions struct {
+	// Tags are the tags which variants may have, most important
+	// first, eg: runtime.GOOS, runtime.GOARCH, "low". Variants with
+	// any other tag aren't used. Of the others, those with the most
+	// important tags are preferred, then those with more tags.
+	Tags []string
+
+	// Scale is the preferred scale, eg: 2 on high density displays.
+	// It's compared after the tags: the variant with the same scale
+	// is preferred, then the smallest larger scale, then the largest
+	// smaller one. If zero, it is 1.
+	Scale float64
+}
+
+// DefaultVariants prefers variants tagged with runtime.GOOS, then
+// runtime.GOARCH, at a scale of 1.
+func DefaultVariants() VariantOptions {
+	return VariantOptions{Tags: []string{runtime.GOOS, runtime.GOARCH}, Scale: 1}
+}
+
+// A Variant is a variant of a resource, with the path of the resource
+// it is a variant of.
+type Variant struct {
+	Resource // the variant, with its own path
+	Logical  string
+	Tags     []string
+	Scale    float64 // 1 if not given
+}
+
+// parseVariant parses the rest of the base name of a variant after the
+// stem of its logical resource, and before its extension.
+func parseVariant(rest string) (tags []string, scale float64, ok bool) {
+	scale = 1
+	if strings.HasPrefix(rest, "@") {
+		i := strings.Index(rest, "x")
+		if i < 0 {
+			return nil, 0, false
+		}
+		s, err := strconv.ParseFloat(rest[1:i], 64)
+		if err != nil || s <= 0 {
+			return nil, 0, false
+		}
+		scale, rest = s, rest[i+1:]
+	}
+	if rest == "" {
+		return nil, scale, true
+	}
+	if rest[0] != '.' {
+		return nil, 0, false
+	}
+	tags = strings.Split(rest[1:], ".")
+	for _, tag := range tags {
+		if tag == "" {
+			return nil, 0, false
+		}
+	}
+	return tags, scale, true
+}
+
+// escapeGlob quotes the characters of s which are special in
+// path.Match patterns.
+func escapeGlob(s string) string {
+	var b strings.Builder
+	for _, c := range s {
+		if strings.ContainsRune(`*?[\`, c) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// splitExt splits a path into the part before its extension, and the
+// extension.
+func splitExt(p string) (string, string) {
+	ext := path.Ext(p)
+	if ext == path.Base(p) {
+		ext = ""
+	}
+	return p[:len(p)-len(ext)], ext
+}
+
+// A Variants bundle uses the best variant of each resource, as
+// described by its VariantOptions. Finding variants requires the
+// wrapped bundle to implement Searcher; otherwise resources are used
+// as they are.
+//
+// Resources it finds have their logical path as their Path, and read
+// the variant returned by Best.
+//
+// It implements Searcher and Lister, which find nothing if the
+// wrapped bundle doesn't implement them. Listing returns resources by
+// their logical paths, once each, with the variant they would be
+// opened with. Only variants with one of the options' tags, or a
+// scale, are recognised as such when listing. Closing it closes the
+// wrapped bundle.
+type Variants struct {
+	bundle Bundle
+	opts   VariantOptions
+	tags   map[string]bool
+}
+
+// OpenVariants wraps b to use the variants of its resources preferred
+// by opts.
+func OpenVariants(b Bundle, opts VariantOptions) *Variants {
+	if opts.Scale <= 0 {
+		opts.Scale = 1
+	}
+	vb := &Variants{bundle: b, opts: opts, tags: make(map[string]bool)}
+	for _, tag := range opts.Tags {
+		vb.tags[tag] = true
+	}
+	return vb
+}
+
+// acceptable reports whether a variant only has wanted tags.
+func (vb *Variants) acceptable(v *Variant) bool {
+	for _, tag := range v.Tags {
+		if !vb.tags[tag] {
+			return false
+		}
+	}
+	return true
+}
+
+// better reports whether the acceptable variant a is preferred to b.
+func (vb *Variants) better(a, b *Variant) bool {
+	// Compare the tags, most important first.
+	for _, tag := range vb.opts.Tags {
+		has_a, has_b := hasTag(a, tag), hasTag(b, tag)
+		if has_a != has_b {
+			return has_a
+		}
+	}
+	if len(a.Tags) != len(b.Tags) {
+		return len(a.Tags) > len(b.Tags)
+	}
+
+	want := vb.opts.Scale
+	switch {
+	case a.Scale == b.Scale:
+		return false
+	case a.Scale == want || b.Scale == want:
+		return a.Scale == want
+	case a.Scale > want && b.Scale > want:
+		return a.Scale < b.Scale
+	case a.Scale < want && b.Scale < want:
+		return a.Scale > b.Scale
+	}
+	return a.Scale > want
+}
+
+func hasTag(v *Variant, tag string) bool {
+	for _, t := range v.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// Variants returns every variant of the resource at logical, including
+// the resource itself, whether or not they would be used.
+func (vb *Variants) Variants(logical string) ([]*Variant, error) {
+	searcher, ok := vb.bundle.(Searcher)
+	if !ok {
+		return nil, nil
+	}
+	stem, ext := splitExt(logical)
+	matches, err := searcher.Glob(escapeGlob(stem) + "*" + escapeGlob(ext))
+	if err != nil {
+		return nil, err
+	}
+	var variants []*Variant
+	for _, rsrc := range matches {
+		p := rsrc.Path()
+		if len(p) < len(stem)+len(ext) {
+			continue
+		}
+		tags, scale, ok := parseVariant(p[len(stem) : len(p)-len(ext)])
+		if ok {
+			variants = append(variants, &Variant{rsrc, logical, tags, scale})
+		}
+	}
+	return variants, nil
+}
+
+// Best returns the preferred variant of the resource at logical.
+func (vb *Variants) Best(logical string) (*Variant, error) {
+	variants, err := vb.Variants(logical)
+	if err != nil {
+		return nil, err
+	}
+	var best *Variant
+	for _, v := range variants {
+		if vb.acceptable(v) && (best == nil || vb.better(v, best)) {
+			best = v
+		}
+	}
+	if best == nil {
+		return nil, ErrNotFound
+	}
+	return best, nil
+}
+
+// logicalResource is a variant found by its logical path.
+type logicalResource struct {
+	*Variant
+}
+
+func (lr logicalResource) Path() string {
+	return lr.Logical
+}
+
+func (lr logicalResource) String() string {
+	return lr.Logical
+}
+
+func (vb *Variants) wrapped() Bundle {
+	return vb.bundle
+}
+
+func (vb *Variants) Close() error {
+	return vb.bundle.Close()
+}
+
+func (vb *Variants) Open(path string) (io.ReadCloser, error) {
+	if _, ok := vb.bundle.(Searcher); !ok {
+		return vb.bundle.Open(path)
+	}
+	v, err := vb.Best(path)
+	if err != nil {
+		return nil, err
+	}
+	return v.Open()
+}
+
+func (vb *Variants) Find(path string) (Resource, error) {
+	v, err := vb.Best(path)
+	if err != nil {
+		return nil, err
+	}
+	return logicalResource{v}, nil
+}
+
+// Glob matches the pattern against the logical paths of the
+// resources, returning the variant of each which Find returns. Without
+// a Lister, the resources are found by globbing every resource in the
+// directories the pattern matches, as variants are in the same
+// directory as their logical path.
+func (vb *Variants) Glob(pattern string) ([]Resource, error) {
+	searcher, ok := vb.bundle.(Searcher)
+	if !ok {
+		return nil, nil
+	}
+	if _, ok := vb.bundle.(Lister); ok {
+		list, err := vb.List()
+		if err != nil {
+			return nil, err
+		}
+		return matchResources(pattern, list)
+	}
+
+	dir, _ := path.Split(pattern)
+	found, err := searcher.Glob(dir + "*")
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	var matches []Resource
+	for _, rsrc := range found {
+		logical := vb.listedVariant(rsrc).Logical
+		if seen[logical] {
+			continue
+		}
+		seen[logical] = true
+		if match, err := path.Match(pattern, logical); err != nil {
+			return nil, err
+		} else if !match {
+			continue
+		}
+		v, err := vb.Best(logical)
+		if err == ErrNotFound {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		matches = append(matches, logicalResource{v})
+	}
+	return matches, nil
+}
+
+// matchResources returns the resources whose paths match pattern.
+func matchResources(pattern string, rsrcs []Resource) ([]Resource, error) {
+	var matches []Resource
+	for _, rsrc := range rsrcs {
+		if match, err := path.Match(pattern, rsrc.Path()); err != nil {
+			return nil, err
+		} else if match {
+			matches = append(matches, rsrc)
+		}
+	}
+	return matches, nil
+}
+
+// listedVariant parses the path of a listed resource as a variant,
+// recognising only the options' tags, and scales.
+func (vb *Variants) listedVariant(rsrc Resource) *Variant {
+	dir, base := path.Split(rsrc.Path())
+	stem, ext := splitExt(base)
+	var tags []string
+	for {
+		i := strings.LastIndex(stem, ".")
+		if i <= 0 {
+			break
+		}
+		if !vb.tags[stem[i+1:]] {
+			break
+		}
+		tags = append([]string{stem[i+1:]}, tags...)
+		stem = stem[:i]
+	}
+	scale := 1.0
+	if i := strings.Index(stem, "@"); i > 0 {
+		if t, s, ok := parseVariant(stem[i:]); ok && t == nil {
+			stem, scale = stem[:i], s
+		}
+	}
+	return &Variant{rsrc, dir + stem + ext, tags, scale}
+}
+
+func (vb *Variants) List() ([]Resource, error) {
+	lister, ok := vb.bundle.(Lister)
+	if !ok {
+		return nil, nil
+	}
+	rsrcs, err := lister.List()
+	if err != nil {
+		return nil, err
+	}
+	best := make(map[string]*Variant)
+	var order []string
+	for _, rsrc := range rsrcs {
+		v := vb.listedVariant(rsrc)
+		prev, ok := best[v.Logical]
+		if !ok {
+			order = append(order, v.Logical)
+		}
+		if !ok || vb.better(v, prev) {
+			best[v.Logical] = v
+		}
+	}
+	list := make([]Resource, len(order))
+	for i, p := range order {
+		list[i] = logicalResource{best[p]}
+	}
+	return list, nil
+}
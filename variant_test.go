@@ -0,0 +1,124 @@
+package resources
+
+import (
+	"reflect"
+	. "testing"
+)
+
+var Variants_Is_A_Bundle Bundle = &Variants{}
+var Variants_Is_A_Searcher Searcher = &Variants{}
+var Variants_Is_A_Lister Lister = &Variants{}
+
+func TestVariants(t *T) {
+	b := zipOfFiles(t, map[string]string{
+		"icon.png":              "1x",
+		"icon@2x.png":           "2x",
+		"icon@3x.png":           "3x",
+		"iconic.png":            "other",
+		"shader.glsl":           "generic",
+		"shader.linux.glsl":     "linux",
+		"shader.windows.glsl":   "windows",
+		"shader.linux.arm.glsl": "linux arm",
+		"music.ogg":             "high",
+		"music.low.ogg":         "low",
+		"dir/a[1].txt":          "a1",
+	})
+
+	for _, test := range []struct {
+		opts VariantOptions
+		want map[string]string
+	}{
+		{
+			VariantOptions{Tags: []string{"linux", "amd64"}, Scale: 2},
+			map[string]string{"icon.png": "2x", "shader.glsl": "linux", "music.ogg": "high", "dir/a[1].txt": "a1"},
+		},
+		{
+			VariantOptions{Tags: []string{"windows", "low"}, Scale: 1.5},
+			map[string]string{"icon.png": "2x", "shader.glsl": "windows", "music.ogg": "low"},
+		},
+		{
+			VariantOptions{Tags: []string{"arm", "linux"}, Scale: 4},
+			map[string]string{"icon.png": "3x", "shader.glsl": "linux arm"},
+		},
+		{
+			VariantOptions{},
+			map[string]string{"icon.png": "1x", "shader.glsl": "generic", "iconic.png": "other"},
+		},
+	} {
+		vb := OpenVariants(b, test.opts)
+		for p, want := range test.want {
+			if got := readAll(t, vb, p); got != want {
+				t.Errorf("%+v: Open(%s) = %q, want %q", test.opts, p, got, want)
+			}
+		}
+	}
+
+	vb := OpenVariants(b, VariantOptions{Tags: []string{"linux"}, Scale: 2})
+	variants, err := vb.Variants("shader.glsl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, v := range variants {
+		got = append(got, v.Path())
+	}
+	t.Log(got)
+	if len(got) != 4 {
+		t.Errorf("Variants(shader.glsl) = %v, want 4", got)
+	}
+
+	rsrc, err := vb.Find("icon.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rsrc.Path() != "icon.png" || rsrc.(logicalResource).Variant.Resource.Path() != "icon@2x.png" {
+		t.Errorf("Find(icon.png) = %v", rsrc.(logicalResource).Variant.Resource)
+	}
+
+	list, err := vb.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Log(paths(list))
+	want := []string{"dir/a[1].txt", "icon.png", "iconic.png", "music.low.ogg", "music.ogg", "shader.glsl", "shader.linux.arm.glsl", "shader.windows.glsl"}
+	if !reflect.DeepEqual(paths(list), want) {
+		t.Errorf("List() = %v, want %v", paths(list), want)
+	}
+	for _, rsrc := range list {
+		if rsrc.Path() == "shader.glsl" {
+			if v := rsrc.(logicalResource).Variant; v.Resource.Path() != "shader.linux.glsl" {
+				t.Errorf("List() chose %s for shader.glsl", v.Resource.Path())
+			}
+		}
+	}
+
+	if _, err := vb.Open("missing.png"); err != ErrNotFound {
+		t.Errorf("Open(missing.png) = %v, want ErrNotFound", err)
+	}
+
+	// Without a Lister, Glob still finds the variants Find would.
+	vb = OpenVariants(searchBundle{b, b.(Searcher)}, VariantOptions{Tags: []string{"linux"}, Scale: 2})
+	for pattern, want := range map[string]map[string]string{
+		"icon*.png":   {"icon.png": "icon@2x.png", "iconic.png": "iconic.png"},
+		"shader.glsl": {"shader.glsl": "shader.linux.glsl"},
+		"dir/*.txt":   {"dir/a[1].txt": "dir/a[1].txt"},
+	} {
+		matches, err := vb.Glob(pattern)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := make(map[string]string)
+		for _, rsrc := range matches {
+			got[rsrc.Path()] = rsrc.(logicalResource).Variant.Resource.Path()
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Glob(%s) without a Lister = %v, want %v", pattern, got, want)
+		}
+	}
+}
+
+// searchBundle hides the Lister of a bundle.
+type searchBundle struct {
+	Bundle
+	Searcher
+}
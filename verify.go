@@ -0,0 +1,293 @@
+package resources
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+)
+
+// ErrBadSignature is returned when a manifest's signature doesn't
+// match its contents.
+var ErrBadSignature = errors.New("resources: manifest signature invalid")
+
+// An IntegrityError reports a resource which doesn't match the
+// manifest of a verified bundle.
+type IntegrityError struct {
+	Path string
+
+	// Want and Got are the SHA-256 hashes of the resource according
+	// to the manifest, and as read. Want is nil if the resource is
+	// missing from the manifest, and Got is nil if it wasn't read.
+	Want, Got []byte
+}
+
+func (e *IntegrityError) Error() string {
+	if e.Want == nil {
+		return fmt.Sprintf("resources: %s: not in manifest", e.Path)
+	}
+	return fmt.Sprintf("resources: %s: hash mismatch (want %x, got %x)", e.Path, e.Want, e.Got)
+}
+
+// A Manifest records the SHA-256 hash of each resource in a bundle.
+//
+// The text form of a Manifest is the same as the output of the
+// sha256sum command: a line for each resource, containing the hex
+// encoded hash, two spaces, and the path.
+type Manifest struct {
+	Hashes map[string][]byte
+}
+
+// BuildManifest hashes every resource listed by src, skipping
+// directories.
+func BuildManifest(src Lister) (*Manifest, error) {
+	list, err := src.List()
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Manifest{Hashes: make(map[string][]byte)}
+	for _, rsrc := range list {
+		if info, err := rsrc.Stat(); err != nil {
+			return nil, err
+		} else if info.IsDir() {
+			continue
+		}
+
+		rdr, err := rsrc.Open()
+		if err != nil {
+			return nil, err
+		}
+		h := sha256.New()
+		_, err = io.Copy(h, rdr)
+		rdr.Close()
+		if err != nil {
+			return nil, err
+		}
+		m.Hashes[rsrc.Path()] = h.Sum(nil)
+	}
+	return m, nil
+}
+
+// ParseManifest parses the text form of a manifest.
+func ParseManifest(data []byte) (*Manifest, error) {
+	m := &Manifest{Hashes: make(map[string][]byte)}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		if text == "" {
+			continue
+		}
+		sum, path, ok := strings.Cut(text, "  ")
+		hash, err := hex.DecodeString(sum)
+		if !ok || err != nil || len(hash) != sha256.Size {
+			return nil, fmt.Errorf("resources: manifest line %d: invalid entry", line)
+		}
+		m.Hashes[path] = hash
+	}
+	return m, scanner.Err()
+}
+
+// MarshalText returns the text form of the manifest, sorted by path.
+func (m *Manifest) MarshalText() ([]byte, error) {
+	paths := make([]string, 0, len(m.Hashes))
+	for path := range m.Hashes {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	buf := new(bytes.Buffer)
+	for _, path := range paths {
+		fmt.Fprintf(buf, "%x  %s\n", m.Hashes[path], path)
+	}
+	return buf.Bytes(), nil
+}
+
+// SignManifest returns the signature of the text form of a manifest,
+// as base64 encoded text.
+func SignManifest(data []byte, key ed25519.PrivateKey) []byte {
+	sig := base64.StdEncoding.EncodeToString(ed25519.Sign(key, data))
+	return []byte(sig + "\n")
+}
+
+// VerifyManifest checks the signature of the text form of a manifest,
+// as made by SignManifest, before parsing it. It returns
+// ErrBadSignature if the signature doesn't match.
+func VerifyManifest(data, sig []byte, key ed25519.PublicKey) (*Manifest, error) {
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(sig)))
+	if err != nil || !ed25519.Verify(key, data, raw) {
+		return nil, ErrBadSignature
+	}
+	return ParseManifest(data)
+}
+
+func readResource(b Bundle, path string) ([]byte, error) {
+	rdr, err := b.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer rdr.Close()
+	return ioutil.ReadAll(rdr)
+}
+
+// LoadManifest reads a manifest stored at path in a bundle. If key
+// is not nil, the manifest must be signed, with the signature stored
+// beside it with a ".sig" extension.
+func LoadManifest(b Bundle, path string, key ed25519.PublicKey) (*Manifest, error) {
+	data, err := readResource(b, path)
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		return ParseManifest(data)
+	}
+	sig, err := readResource(b, path+".sig")
+	if err != nil {
+		return nil, err
+	}
+	return VerifyManifest(data, sig, key)
+}
+
+// verifyingReader checks the hash of the data read through it when
+// it reaches the end.
+type verifyingReader struct {
+	io.ReadCloser
+	path string
+	want []byte
+	h    hash.Hash
+}
+
+func newVerifyingReader(rdr io.ReadCloser, path string, want []byte) io.ReadCloser {
+	return &verifyingReader{rdr, path, want, sha256.New()}
+}
+
+func (vr *verifyingReader) Read(p []byte) (int, error) {
+	n, err := vr.ReadCloser.Read(p)
+	vr.h.Write(p[:n])
+	if err == io.EOF {
+		if got := vr.h.Sum(nil); !bytes.Equal(got, vr.want) {
+			return n, &IntegrityError{Path: vr.path, Want: vr.want, Got: got}
+		}
+	}
+	return n, err
+}
+
+type verifiedResource struct {
+	Resource
+	want []byte
+}
+
+func (vr *verifiedResource) Open() (io.ReadCloser, error) {
+	rdr, err := vr.Resource.Open()
+	if err != nil {
+		return nil, err
+	}
+	return newVerifyingReader(rdr, vr.Path(), vr.want), nil
+}
+
+func (vr *verifiedResource) String() string {
+	return vr.Path()
+}
+
+type verifiedBundle struct {
+	bundle   Bundle
+	manifest *Manifest
+}
+
+// OpenVerified wraps a bundle so that only resources in the manifest
+// can be used, and their contents are checked against it.
+//
+// The hash of a resource is checked as it is read, so the reader
+// returned by Open returns an *IntegrityError instead of io.EOF if it
+// doesn't match. Callers must read until io.EOF before trusting the
+// data. Opening a resource which exists but isn't in the manifest
+// also returns an *IntegrityError, and such resources are left out
+// of the results of Glob and List.
+//
+// The returned Bundle implements Searcher and Lister, which find
+// nothing if the wrapped bundle doesn't implement them. Closing it
+// closes the wrapped bundle.
+func OpenVerified(b Bundle, m *Manifest) Bundle {
+	return &verifiedBundle{bundle: b, manifest: m}
+}
+
+func (vb *verifiedBundle) wrapped() Bundle {
+	return vb.bundle
+}
+
+func (vb *verifiedBundle) Close() error {
+	return vb.bundle.Close()
+}
+
+func (vb *verifiedBundle) Open(path string) (io.ReadCloser, error) {
+	want, ok := vb.manifest.Hashes[path]
+	rdr, err := vb.bundle.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		rdr.Close()
+		return nil, &IntegrityError{Path: path}
+	}
+	return newVerifyingReader(rdr, path, want), nil
+}
+
+func (vb *verifiedBundle) Find(path string) (Resource, error) {
+	searcher, ok := vb.bundle.(Searcher)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	rsrc, err := searcher.Find(path)
+	if err != nil {
+		return nil, err
+	}
+	want, ok := vb.manifest.Hashes[path]
+	if !ok {
+		return nil, &IntegrityError{Path: path}
+	}
+	return &verifiedResource{rsrc, want}, nil
+}
+
+// filter returns the resources which are in the manifest.
+func (vb *verifiedBundle) filter(rsrcs []Resource) []Resource {
+	var out []Resource
+	for _, rsrc := range rsrcs {
+		if want, ok := vb.manifest.Hashes[rsrc.Path()]; ok {
+			out = append(out, &verifiedResource{rsrc, want})
+		}
+	}
+	return out
+}
+
+func (vb *verifiedBundle) Glob(pattern string) ([]Resource, error) {
+	searcher, ok := vb.bundle.(Searcher)
+	if !ok {
+		return nil, nil
+	}
+	rsrcs, err := searcher.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+	return vb.filter(rsrcs), nil
+}
+
+func (vb *verifiedBundle) List() ([]Resource, error) {
+	lister, ok := vb.bundle.(Lister)
+	if !ok {
+		return nil, nil
+	}
+	rsrcs, err := lister.List()
+	if err != nil {
+		return nil, err
+	}
+	return vb.filter(rsrcs), nil
+}
@@ -0,0 +1,63 @@
+package resources
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"io/ioutil"
+	. "testing"
+)
+
+var VerifiedBundle_Is_A_Searcher Searcher = &verifiedBundle{}
+var VerifiedBundle_Is_A_Lister Lister = &verifiedBundle{}
+
+func TestVerified(t *T) {
+	zip := CreateTestZip(t)
+	zb, err := OpenZipReader(zip, int64(zip.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := BuildManifest(zb.(Lister))
+	if err != nil {
+		t.Fatal("BuildManifest():", err)
+	}
+
+	// Round trip through the signed text form.
+	pub, priv, _ := ed25519.GenerateKey(nil)
+	data, _ := m.MarshalText()
+	t.Logf("Manifest:\n%s", data)
+	sig := SignManifest(data, priv)
+	if m, err = VerifyManifest(data, sig, pub); err != nil {
+		t.Fatal("VerifyManifest():", err)
+	}
+	data[0] ^= 1
+	if _, err := VerifyManifest(data, sig, pub); err != ErrBadSignature {
+		t.Errorf("VerifyManifest(tampered) = %v, want ErrBadSignature", err)
+	}
+
+	delete(m.Hashes, "MANIFEST")
+	m.Hashes["foo.txt"] = m.Hashes["logo.ico"]
+	vb := OpenVerified(zb, m)
+
+	if got := readAll(t, vb, "subfolder/bar.txt"); got != "bar is not foo" {
+		t.Errorf("Open(subfolder/bar.txt) = %q", got)
+	}
+
+	var ierr *IntegrityError
+	rdr, err := vb.Open("foo.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ioutil.ReadAll(rdr); !errors.As(err, &ierr) || ierr.Want == nil {
+		t.Errorf("reading modified foo.txt: %v, want hash mismatch", err)
+	}
+
+	if _, err := vb.Open("MANIFEST"); !errors.As(err, &ierr) || ierr.Want != nil {
+		t.Errorf("Open(unlisted MANIFEST): %v, want not in manifest", err)
+	}
+	if _, err := vb.Open("missing"); err != ErrNotFound {
+		t.Errorf("Open(missing): %v, want ErrNotFound", err)
+	}
+	if list, _ := vb.(Lister).List(); len(list) != len(files) {
+		t.Errorf("List() = %v, want %d resources", list, len(files))
+	}
+}
@@ -0,0 +1,214 @@
+package resources
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// A Watcher reports changes to resources, eg: so that caches of them
+// can be invalidated.
+type Watcher interface {
+	// Watch calls fn with the path of each resource which is
+	// added, modified, or removed, until cancel is called.
+	// Calls to fn may come from any goroutine.
+	Watch(fn func(path string)) (cancel func())
+}
+
+// pollState is what a Poller remembers of a resource.
+type pollState struct {
+	size    int64
+	modtime time.Time
+}
+
+// DefaultPollInterval is the interval of a Poller if none is given.
+const DefaultPollInterval = time.Second
+
+// A Poller is a Watcher which detects changes by listing the resources
+// of a Lister at a fixed interval, and comparing their sizes and
+// modification times. It only polls while something is watching it.
+type Poller struct {
+	// OnError, if not nil, is called with the errors of the polls
+	// made at each interval. It must be set before Watch is called.
+	OnError func(error)
+
+	lister   Lister
+	interval time.Duration
+
+	poll_mu sync.Mutex // serializes polls
+
+	mu       sync.Mutex
+	watchers map[int]func(string)
+	next_id  int
+	state    map[string]pollState
+	err      error
+	stop     chan struct{}
+}
+
+// NewPoller returns a Poller which lists the resources of l every
+// interval, or every DefaultPollInterval if it isn't positive.
+func NewPoller(l Lister, interval time.Duration) *Poller {
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+	return &Poller{lister: l, interval: interval, watchers: make(map[int]func(string))}
+}
+
+// Err returns the error of the last poll, or nil if it succeeded.
+func (p *Poller) Err() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.err
+}
+
+// Watch implements the Watcher interface.
+func (p *Poller) Watch(fn func(path string)) (cancel func()) {
+	p.mu.Lock()
+	id := p.next_id
+	p.next_id++
+	p.watchers[id] = fn
+	if p.stop == nil {
+		p.stop = make(chan struct{})
+		go p.run(p.stop)
+	}
+	first := p.state == nil
+	p.mu.Unlock()
+
+	// Record the state now, so changes after Watch returns are seen.
+	if first {
+		p.Poll()
+	}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			p.mu.Lock()
+			defer p.mu.Unlock()
+			delete(p.watchers, id)
+			if len(p.watchers) == 0 && p.stop != nil {
+				close(p.stop)
+				p.stop = nil
+			}
+		})
+	}
+}
+
+func (p *Poller) run(stop chan struct{}) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if err := p.Poll(); err != nil && p.OnError != nil {
+				p.OnError(err)
+			}
+		}
+	}
+}
+
+// Poll lists the resources now, and reports any changes since the
+// last poll to the watchers. The first poll only records the state of
+// the resources. Polls are made one at a time, so watchers mustn't
+// call Poll.
+func (p *Poller) Poll() error {
+	p.poll_mu.Lock()
+	defer p.poll_mu.Unlock()
+
+	list, err := p.lister.List()
+	p.mu.Lock()
+	p.err = err
+	p.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	state := make(map[string]pollState, len(list))
+	for _, rsrc := range list {
+		if info, err := rsrc.Stat(); err == nil {
+			state[rsrc.Path()] = pollState{info.Size(), info.ModTime()}
+		}
+	}
+
+	p.mu.Lock()
+	old := p.state
+	p.state = state
+	var watchers []func(string)
+	for _, fn := range p.watchers {
+		watchers = append(watchers, fn)
+	}
+	p.mu.Unlock()
+	if old == nil {
+		return nil
+	}
+
+	var changed []string
+	for path, st := range state {
+		if prev, ok := old[path]; !ok || prev.size != st.size || !prev.modtime.Equal(st.modtime) {
+			changed = append(changed, path)
+		}
+	}
+	for path := range old {
+		if _, ok := state[path]; !ok {
+			changed = append(changed, path)
+		}
+	}
+	for _, path := range changed {
+		for _, fn := range watchers {
+			fn(path)
+		}
+	}
+	return nil
+}
+
+type watchedBundle struct {
+	bundle Bundle
+	w      Watcher
+}
+
+// Watched returns a Bundle with the resources of b, which is also a
+// Watcher reporting the changes seen by w, eg: a Poller of b. As a
+// layer of a Sequence or SearchPath, it invalidates their LookupCache.
+//
+// The returned Bundle implements Searcher and Lister, which find
+// nothing if b doesn't implement them. Closing it closes b.
+func Watched(b Bundle, w Watcher) Bundle {
+	return &watchedBundle{b, w}
+}
+
+func (wb *watchedBundle) Watch(fn func(path string)) (cancel func()) {
+	return wb.w.Watch(fn)
+}
+
+func (wb *watchedBundle) Open(path string) (io.ReadCloser, error) {
+	return wb.bundle.Open(path)
+}
+
+func (wb *watchedBundle) Find(path string) (Resource, error) {
+	if searcher, ok := wb.bundle.(Searcher); ok {
+		return searcher.Find(path)
+	}
+	return nil, ErrNotFound
+}
+
+func (wb *watchedBundle) Glob(pattern string) ([]Resource, error) {
+	if searcher, ok := wb.bundle.(Searcher); ok {
+		return searcher.Glob(pattern)
+	}
+	return nil, nil
+}
+
+func (wb *watchedBundle) List() ([]Resource, error) {
+	if lister, ok := wb.bundle.(Lister); ok {
+		return lister.List()
+	}
+	return nil, nil
+}
+
+func (wb *watchedBundle) wrapped() Bundle {
+	return wb.bundle
+}
+
+func (wb *watchedBundle) Close() error {
+	return wb.bundle.Close()
+}
@@ -5,10 +5,21 @@ import (
 	"io"
 	"os"
 	"path"
+	"strings"
 )
 
 type zipResource struct {
 	*zip.File
+	zb *zipBundle
+}
+
+// Open the resource for reading, decrypting it with the
+// bundle's password if it is encrypted.
+func (zr *zipResource) Open() (io.ReadCloser, error) {
+	if zr.Flags&zipFlagEncrypted != 0 {
+		return zr.zb.openEncrypted(zr.File)
+	}
+	return zr.File.Open()
 }
 
 func (zr *zipResource) Path() string {
@@ -24,8 +35,21 @@ func (zr *zipResource) String() string {
 }
 
 type zipBundle struct {
-	file *os.File
-	rdr  *zip.Reader
+	file     *os.File
+	rdr      *zip.Reader
+	password []byte
+}
+
+// A ZipOption configures a bundle opened by OpenZip or OpenZipReader.
+type ZipOption func(*zipBundle)
+
+// WithPassword sets the password used to read encrypted entries in
+// the zip file. Both WinZip AES and traditional PKWARE encryption
+// are supported.
+func WithPassword(password string) ZipOption {
+	return func(zb *zipBundle) {
+		zb.password = []byte(password)
+	}
 }
 
 // Closes the ZipBundle's associated file, if
@@ -52,7 +76,7 @@ func (zb *zipBundle) Open(path string) (io.ReadCloser, error) {
 func (zb *zipBundle) Find(path string) (Resource, error) {
 	for _, file := range zb.rdr.File {
 		if file.Name == path {
-			return &zipResource{file}, nil
+			return &zipResource{file, zb}, nil
 		}
 	}
 	return nil, ErrNotFound
@@ -62,7 +86,7 @@ func (zb *zipBundle) Find(path string) (Resource, error) {
 func (zb *zipBundle) Glob(pattern string) (resources []Resource, err error) {
 	for _, file := range zb.rdr.File {
 		if match, err := path.Match(pattern, file.Name); match {
-			resources = append(resources, &zipResource{file})
+			resources = append(resources, &zipResource{file, zb})
 		} else if err != nil {
 			return nil, err
 		}
@@ -73,7 +97,7 @@ func (zb *zipBundle) Glob(pattern string) (resources []Resource, err error) {
 // Lists all resources in the ZipBundle
 func (zb *zipBundle) List() (list []Resource, err error) {
 	for _, file := range zb.rdr.File {
-		list = append(list, &zipResource{file})
+		list = append(list, &zipResource{file, zb})
 	}
 	return
 }
@@ -86,7 +110,10 @@ func (zb *zipBundle) List() (list []Resource, err error) {
 //
 // If the file is in a known executable format,
 // it is searched for an embedded zip file.
-func OpenZip(path string) (Bundle, error) {
+//
+// Encrypted entries can only be read if a password is
+// given with the WithPassword option.
+func OpenZip(path string, opts ...ZipOption) (Bundle, error) {
 	file, err := os.Open(path)
 	if err != nil {
 		return nil, err
@@ -95,14 +122,31 @@ func OpenZip(path string) (Bundle, error) {
 	if err != nil {
 		return nil, err
 	}
-	zb, err := OpenZipReader(file, finfo.Size())
+	zb, err := OpenZipReader(file, finfo.Size(), opts...)
 	if err != nil {
+		file.Close()
 		return nil, err
 	}
 	zb.(*zipBundle).file = file
 	return zb, nil
 }
 
+// OpenZipData opens a zipfile held in memory, such as one
+// compiled into the program by the resources-gen command.
+func OpenZipData(data string) (Bundle, error) {
+	return OpenZipReader(strings.NewReader(data), int64(len(data)))
+}
+
+// MustOpenZipData is like OpenZipData but panics if the data
+// can't be opened. It is intended for initializing variables.
+func MustOpenZipData(data string) Bundle {
+	zb, err := OpenZipData(data)
+	if err != nil {
+		panic(err)
+	}
+	return zb
+}
+
 // Opens a zipfile specified by the given ReaderAt and size.
 // Close() is a no-op on the returned structure, ie: you must
 // close the reader's resource yourself if necessary.
@@ -112,7 +156,10 @@ func OpenZip(path string) (Bundle, error) {
 //
 // If the reader accesses data for a known executable format,
 // it will be searched for an embedded zip file.
-func OpenZipReader(rda io.ReaderAt, size int64) (Bundle, error) {
+//
+// Encrypted entries can only be read if a password is
+// given with the WithPassword option.
+func OpenZipReader(rda io.ReaderAt, size int64, opts ...ZipOption) (Bundle, error) {
 	rdr, err := zip.NewReader(rda, size)
 	if err != nil {
 		rdr2, err2 := zipExeReader(rda, size)
@@ -121,5 +168,9 @@ func OpenZipReader(rda io.ReaderAt, size int64) (Bundle, error) {
 		}
 		rdr = rdr2
 	}
-	return &zipBundle{rdr: rdr}, nil
+	zb := &zipBundle{rdr: rdr}
+	for _, opt := range opts {
+		opt(zb)
+	}
+	return zb, nil
 }
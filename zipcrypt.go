@@ -0,0 +1,332 @@
+package resources
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/pbkdf2"
+	"crypto/sha1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+)
+
+const (
+	zipFlagEncrypted  = 0x1
+	zipFlagDescriptor = 0x8
+
+	// WinZip AES entries use this method, with the real
+	// compression method stored in their extra field.
+	zipMethodAES   = 99
+	zipExtraAES    = 0x9901
+	zipAESAuthSize = 10
+	zipAESIter     = 1000
+)
+
+var (
+	// ErrPasswordRequired is the cause of a PasswordError when
+	// reading an encrypted entry from a zip file opened without
+	// a password.
+	ErrPasswordRequired = errors.New("password required")
+
+	// ErrWrongPassword is the cause of a PasswordError when the
+	// password doesn't match an encrypted entry.
+	ErrWrongPassword = errors.New("wrong password")
+)
+
+// A PasswordError is returned when opening an encrypted entry in a
+// zip file fails because the password is missing or wrong. Its Err
+// is ErrPasswordRequired or ErrWrongPassword.
+type PasswordError struct {
+	Path string
+	Err  error
+}
+
+func (e *PasswordError) Error() string {
+	return fmt.Sprintf("resources: %s: %v", e.Path, e.Err)
+}
+
+func (e *PasswordError) Unwrap() error {
+	return e.Err
+}
+
+// openEncrypted opens a zip entry encrypted with either WinZip AES
+// or traditional PKWARE encryption. archive/zip can't decrypt
+// entries itself, so the raw data is decrypted here, then
+// decompressed.
+func (zb *zipBundle) openEncrypted(file *zip.File) (io.ReadCloser, error) {
+	if zb.password == nil {
+		return nil, &PasswordError{file.Name, ErrPasswordRequired}
+	}
+	raw, err := file.OpenRaw()
+	if err != nil {
+		return nil, err
+	}
+
+	var plain io.Reader
+	method, check_crc, zip_crypto := file.Method, true, false
+	if file.Method == zipMethodAES {
+		plain, method, check_crc, err = zipAESReader(file, raw, zb.password)
+	} else {
+		plain, err = zipCryptoReader(file, raw, zb.password)
+		zip_crypto = true
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var rc io.ReadCloser
+	switch method {
+	case zip.Store:
+		rc = ioutil.NopCloser(plain)
+	case zip.Deflate:
+		rc = flate.NewReader(plain)
+	default:
+		return nil, zip.ErrAlgorithm
+	}
+	rc = &drainReader{rc, plain}
+	if !check_crc {
+		return rc, nil
+	}
+	rc = &crcReader{ReadCloser: rc, hash: crc32.NewIEEE(), want: file.CRC32}
+	if zip_crypto {
+		rc = &zipCryptoCheck{rc, file.Name}
+	}
+	return rc, nil
+}
+
+// zipCryptoCheck reports the data of a ZipCrypto entry failing to
+// decompress, or its CRC-32 not matching, as a wrong password: the
+// password is only checked against one byte, so 1 in 256 wrong
+// passwords aren't caught until then. Corrupted data is reported the
+// same way.
+type zipCryptoCheck struct {
+	io.ReadCloser
+	name string
+}
+
+func (zc *zipCryptoCheck) Read(p []byte) (int, error) {
+	n, err := zc.ReadCloser.Read(p)
+	var corrupt flate.CorruptInputError
+	if err == zip.ErrChecksum || errors.As(err, &corrupt) {
+		err = &PasswordError{zc.name, ErrWrongPassword}
+	}
+	return n, err
+}
+
+// drainReader reads the rest of the decrypted data when the
+// decompressed data ends, so the end of the encrypted data is
+// reached, and its authentication code is checked.
+type drainReader struct {
+	io.ReadCloser
+	plain io.Reader
+}
+
+func (dr *drainReader) Read(p []byte) (int, error) {
+	n, err := dr.ReadCloser.Read(p)
+	if err == io.EOF {
+		if _, derr := io.Copy(ioutil.Discard, dr.plain); derr != nil {
+			err = derr
+		}
+	}
+	return n, err
+}
+
+// crcReader checks the CRC-32 of the decrypted data at the end.
+type crcReader struct {
+	io.ReadCloser
+	hash hash.Hash32
+	want uint32
+}
+
+func (cr *crcReader) Read(p []byte) (int, error) {
+	n, err := cr.ReadCloser.Read(p)
+	cr.hash.Write(p[:n])
+	if err == io.EOF && cr.hash.Sum32() != cr.want {
+		err = zip.ErrChecksum
+	}
+	return n, err
+}
+
+// zipCrypto holds the key state of the traditional PKWARE stream
+// cipher.
+type zipCrypto struct {
+	keys [3]uint32
+}
+
+func crc32Update(crc uint32, b byte) uint32 {
+	return crc32.IEEETable[byte(crc)^b] ^ (crc >> 8)
+}
+
+func newZipCrypto(password []byte) *zipCrypto {
+	zc := &zipCrypto{[3]uint32{0x12345678, 0x23456789, 0x34567890}}
+	for _, b := range password {
+		zc.update(b)
+	}
+	return zc
+}
+
+func (zc *zipCrypto) update(b byte) {
+	zc.keys[0] = crc32Update(zc.keys[0], b)
+	zc.keys[1] = (zc.keys[1]+zc.keys[0]&0xff)*134775813 + 1
+	zc.keys[2] = crc32Update(zc.keys[2], byte(zc.keys[1]>>24))
+}
+
+func (zc *zipCrypto) decrypt(p []byte) {
+	for i, c := range p {
+		temp := zc.keys[2] | 2
+		p[i] = c ^ byte((temp*(temp^1))>>8)
+		zc.update(p[i])
+	}
+}
+
+type zipCryptoStream struct {
+	r  io.Reader
+	zc *zipCrypto
+}
+
+func (zs *zipCryptoStream) Read(p []byte) (int, error) {
+	n, err := zs.r.Read(p)
+	zs.zc.decrypt(p[:n])
+	return n, err
+}
+
+// zipCryptoReader decrypts the raw data of an entry encrypted with
+// traditional PKWARE encryption.
+func zipCryptoReader(file *zip.File, raw io.Reader, password []byte) (io.Reader, error) {
+	zc := newZipCrypto(password)
+	var header [12]byte
+	if _, err := io.ReadFull(raw, header[:]); err != nil {
+		return nil, err
+	}
+	zc.decrypt(header[:])
+
+	// The last byte of the header checks the password, against the
+	// CRC-32, or the modification time if the entry is followed by a
+	// data descriptor.
+	check := byte(file.CRC32 >> 24)
+	if file.Flags&zipFlagDescriptor != 0 {
+		check = byte(file.ModifiedTime >> 8)
+	}
+	if header[11] != check {
+		return nil, &PasswordError{file.Name, ErrWrongPassword}
+	}
+	return &zipCryptoStream{raw, zc}, nil
+}
+
+// zipCTR is the AES counter mode used by WinZip AES, which counts
+// from one with a little-endian counter.
+type zipCTR struct {
+	block   cipher.Block
+	counter [aes.BlockSize]byte
+	stream  [aes.BlockSize]byte
+	used    int
+}
+
+func (zc *zipCTR) XORKeyStream(dst, src []byte) {
+	for i := range src {
+		if zc.used == len(zc.stream) {
+			for j := range zc.counter {
+				zc.counter[j]++
+				if zc.counter[j] != 0 {
+					break
+				}
+			}
+			zc.block.Encrypt(zc.stream[:], zc.counter[:])
+			zc.used = 0
+		}
+		dst[i] = src[i] ^ zc.stream[zc.used]
+		zc.used++
+	}
+}
+
+// zipAESStream decrypts the data of a WinZip AES entry, and checks
+// its authentication code at the end.
+type zipAESStream struct {
+	data io.Reader // the encrypted data
+	raw  io.Reader // the authentication code follows the data
+	ctr  *zipCTR
+	mac  hash.Hash
+}
+
+func (zs *zipAESStream) Read(p []byte) (int, error) {
+	n, err := zs.data.Read(p)
+	zs.mac.Write(p[:n])
+	zs.ctr.XORKeyStream(p[:n], p[:n])
+	if err == io.EOF {
+		var code [zipAESAuthSize]byte
+		if _, rerr := io.ReadFull(zs.raw, code[:]); rerr != nil {
+			return n, rerr
+		}
+		if !hmac.Equal(code[:], zs.mac.Sum(nil)[:zipAESAuthSize]) {
+			return n, zip.ErrChecksum
+		}
+	}
+	return n, err
+}
+
+// zipAESExtra finds the WinZip AES extra field of an entry, returning
+// the vendor version (1 for AE-1, 2 for AE-2), key strength, and the
+// real compression method.
+func zipAESExtra(extra []byte) (version uint16, strength byte, method uint16, err error) {
+	for len(extra) >= 4 {
+		id, size := binary.LittleEndian.Uint16(extra), int(binary.LittleEndian.Uint16(extra[2:]))
+		extra = extra[4:]
+		if size > len(extra) {
+			break
+		}
+		if id == zipExtraAES && size >= 7 {
+			field := extra[:size]
+			return binary.LittleEndian.Uint16(field), field[4], binary.LittleEndian.Uint16(field[5:]), nil
+		}
+		extra = extra[size:]
+	}
+	return 0, 0, 0, zip.ErrFormat
+}
+
+// zipAESReader decrypts the raw data of an entry encrypted with
+// WinZip AES, and returns the compression method of the decrypted
+// data, and whether its CRC-32 is valid (only for AE-1).
+func zipAESReader(file *zip.File, raw io.Reader, password []byte) (io.Reader, uint16, bool, error) {
+	version, strength, method, err := zipAESExtra(file.Extra)
+	if err != nil || strength < 1 || strength > 3 {
+		return nil, 0, false, zip.ErrFormat
+	}
+	key_len := 8 + 8*int(strength)
+	salt_len := key_len / 2
+
+	header := make([]byte, salt_len+2)
+	if _, err := io.ReadFull(raw, header); err != nil {
+		return nil, 0, false, err
+	}
+	keys, err := pbkdf2.Key(sha1.New, string(password), header[:salt_len], zipAESIter, 2*key_len+2)
+	if err != nil {
+		return nil, 0, false, err
+	}
+	if !bytes.Equal(keys[2*key_len:], header[salt_len:]) {
+		return nil, 0, false, &PasswordError{file.Name, ErrWrongPassword}
+	}
+
+	block, err := aes.NewCipher(keys[:key_len])
+	if err != nil {
+		return nil, 0, false, err
+	}
+	size := int64(file.CompressedSize64) - int64(len(header)) - zipAESAuthSize
+	if size < 0 {
+		return nil, 0, false, zip.ErrFormat
+	}
+	stream := &zipAESStream{
+		data: io.LimitReader(raw, size),
+		raw:  raw,
+		ctr:  &zipCTR{block: block, used: aes.BlockSize},
+		mac:  hmac.New(sha1.New, keys[key_len:2*key_len]),
+	}
+	return stream, method, version == 1, nil
+}
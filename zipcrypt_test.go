@@ -0,0 +1,211 @@
+package resources
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"crypto/aes"
+	"crypto/hmac"
+	"crypto/pbkdf2"
+	"crypto/sha1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	. "testing"
+)
+
+// testdata/zipcrypto.zip was made by Info-ZIP with:
+//	zip -X -P secret zipcrypto.zip foo.txt bar.txt
+var zipCryptoFiles = map[string]string{
+	"foo.txt": "foo is foo",
+	"bar.txt": strings.Repeat("bar is not foo\n", 200),
+}
+
+func checkPasswordError(t *T, zb Bundle, path string, want error) {
+	var perr *PasswordError
+	if _, err := zb.Open(path); !errors.As(err, &perr) || !errors.Is(err, want) {
+		t.Errorf("Open(%s): %v, want PasswordError(%v)", path, err, want)
+	}
+}
+
+func TestZipCrypto(t *T) {
+	for _, password := range []string{"", "wrong", "secret"} {
+		var opts []ZipOption
+		if password != "" {
+			opts = append(opts, WithPassword(password))
+		}
+		zb, err := OpenZip("testdata/zipcrypto.zip", opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer zb.Close()
+
+		for path, contents := range zipCryptoFiles {
+			switch password {
+			case "":
+				checkPasswordError(t, zb, path, ErrPasswordRequired)
+			case "wrong":
+				checkPasswordError(t, zb, path, ErrWrongPassword)
+			default:
+				if got := readAll(t, zb, path); got != contents {
+					t.Errorf("%s - Contents Differ", path)
+				}
+			}
+		}
+	}
+}
+
+// findWeakPassword returns a wrong password for the entry at path
+// which passes the 1-byte ZipCrypto password check.
+func findWeakPassword(t *T, path string) string {
+	for i := 0; i < 100000; i++ {
+		password := fmt.Sprint("wrong", i)
+		zb, err := OpenZip("testdata/zipcrypto.zip", WithPassword(password))
+		if err != nil {
+			t.Fatal(err)
+		}
+		rdr, err := zb.Open(path)
+		zb.Close()
+		if err == nil {
+			rdr.Close()
+			return password
+		}
+	}
+	t.Fatal("no password passes the check")
+	return ""
+}
+
+func TestZipCryptoWeakCheck(t *T) {
+	for path := range zipCryptoFiles {
+		password := findWeakPassword(t, path)
+		zb, err := OpenZip("testdata/zipcrypto.zip", WithPassword(password))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer zb.Close()
+		rdr, err := zb.Open(path)
+		if err != nil {
+			t.Fatalf("Open(%s): %v", path, err)
+		}
+		_, err = ioutil.ReadAll(rdr)
+		rdr.Close()
+		if !errors.Is(err, ErrWrongPassword) {
+			t.Errorf("reading %s with password %q: %v, want ErrWrongPassword", path, password, err)
+		}
+	}
+}
+
+// testdata/aes256.zip was made by libarchive, which implements WinZip
+// AES independently, with:
+//	bsdtar --format zip --options zip:encryption=aes256 \
+//		--passphrase secret -cf aes256.zip foo.txt bar.txt
+// foo.txt is stored (AE-2) and bar.txt deflated (AE-1), both with data
+// descriptors. They have the contents of zipCryptoFiles.
+func TestZipAESFixture(t *T) {
+	for _, password := range []string{"", "wrong", "secret"} {
+		var opts []ZipOption
+		if password != "" {
+			opts = append(opts, WithPassword(password))
+		}
+		zb, err := OpenZip("testdata/aes256.zip", opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer zb.Close()
+
+		for path, contents := range zipCryptoFiles {
+			switch password {
+			case "":
+				checkPasswordError(t, zb, path, ErrPasswordRequired)
+			case "wrong":
+				checkPasswordError(t, zb, path, ErrWrongPassword)
+			default:
+				if got := readAll(t, zb, path); got != contents {
+					t.Errorf("%s - Contents Differ", path)
+				}
+			}
+		}
+	}
+}
+
+// createAESZip returns a zip file containing contents, deflated and
+// encrypted with WinZip AES-256 (AE-2) using password.
+func createAESZip(t *T, name, contents, password string) []byte {
+	deflated := new(bytes.Buffer)
+	fw, _ := flate.NewWriter(deflated, flate.DefaultCompression)
+	fw.Write([]byte(contents))
+	fw.Close()
+
+	const key_len, salt_len = 32, 16
+	salt := []byte("0123456789abcdef")
+	keys, err := pbkdf2.Key(sha1.New, password, salt, zipAESIter, 2*key_len+2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, _ := aes.NewCipher(keys[:key_len])
+	data := deflated.Bytes()
+	(&zipCTR{block: block, used: aes.BlockSize}).XORKeyStream(data, data)
+	mac := hmac.New(sha1.New, keys[key_len:2*key_len])
+	mac.Write(data)
+
+	raw := new(bytes.Buffer)
+	raw.Write(salt)
+	raw.Write(keys[2*key_len:])
+	raw.Write(data)
+	raw.Write(mac.Sum(nil)[:zipAESAuthSize])
+
+	extra := make([]byte, 11)
+	binary.LittleEndian.PutUint16(extra, zipExtraAES)
+	binary.LittleEndian.PutUint16(extra[2:], 7)
+	binary.LittleEndian.PutUint16(extra[4:], 2) // AE-2
+	copy(extra[6:], "AE")
+	extra[8] = 3 // AES-256
+	binary.LittleEndian.PutUint16(extra[9:], zip.Deflate)
+
+	buf := new(bytes.Buffer)
+	zw := zip.NewWriter(buf)
+	w, err := zw.CreateRaw(&zip.FileHeader{
+		Name:               name,
+		Method:             zipMethodAES,
+		Flags:              zipFlagEncrypted,
+		Extra:              extra,
+		CompressedSize64:   uint64(raw.Len()),
+		UncompressedSize64: uint64(len(contents)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write(raw.Bytes())
+	zw.Close()
+	return buf.Bytes()
+}
+
+func TestZipAES(t *T) {
+	contents := strings.Repeat("encrypted with AES\n", 100)
+	data := createAESZip(t, "aes.txt", contents, "secret")
+
+	zb, err := OpenZipReader(bytes.NewReader(data), int64(len(data)), WithPassword("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := readAll(t, zb, "aes.txt"); got != contents {
+		t.Errorf("aes.txt - Contents Differ: %q", got)
+	}
+
+	zb, _ = OpenZipReader(bytes.NewReader(data), int64(len(data)), WithPassword("wrong"))
+	checkPasswordError(t, zb, "aes.txt", ErrWrongPassword)
+
+	// Flip a bit of the encrypted data: the authentication code
+	// must catch it.
+	data[bytes.Index(data, []byte("0123456789abcdef"))+20] ^= 1
+	zb, _ = OpenZipReader(bytes.NewReader(data), int64(len(data)), WithPassword("secret"))
+	rdr, err := zb.Open("aes.txt")
+	if err == nil {
+		_, err = ioutil.ReadAll(rdr)
+	}
+	if err == nil {
+		t.Error("reading modified aes.txt succeeded")
+	}
+}
@@ -37,13 +37,31 @@ func zipExeReaderMacho(rda io.ReaderAt, size int64) (*zip.Reader, error) {
 		return nil, err
 	}
 
+	// A code signature follows everything else, even a zip file in
+	// __LINKEDIT, as by EmbedZip.
+	sig_start := size
+	for _, load := range file.Loads {
+		if raw := load.Raw(); len(raw) >= 16 && file.ByteOrder.Uint32(raw) == lcCodeSignature {
+			if off := int64(file.ByteOrder.Uint32(raw[8:])); off < sig_start {
+				sig_start = off
+			}
+		}
+	}
+
 	var max int64
 	for _, load := range file.Loads {
 		seg, ok := load.(*macho.Segment)
 		if ok {
 			// Check if the segment contains a zip file
-			if zfile, err := zip.NewReader(seg, int64(seg.Filesz)); err == nil {
-				return zfile, nil
+			seg_size := int64(seg.Filesz)
+			if end := int64(seg.Offset) + seg_size; end > sig_start {
+				seg_size -= end - sig_start
+			}
+			if seg_size > 0 {
+				section := io.NewSectionReader(rda, int64(seg.Offset), seg_size)
+				if zfile, err := zip.NewReader(section, seg_size); err == nil {
+					return zfile, nil
+				}
 			}
 
 			// Otherwise move end of file pointer
@@ -55,7 +73,7 @@ func zipExeReaderMacho(rda io.ReaderAt, size int64) (*zip.Reader, error) {
 	}
 
 	// No zip file within binary, try appended to end
-	section := io.NewSectionReader(rda, max, size-max)
+	section := io.NewSectionReader(rda, max, sig_start-max)
 	return zip.NewReader(section, section.Size())
 }
 
@@ -81,8 +99,23 @@ func zipExeReaderPe(rda io.ReaderAt, size int64) (*zip.Reader, error) {
 		}
 	}
 
-	// No zip file within binary, try appended to end
-	section := io.NewSectionReader(rda, max, size-max)
+	// No zip file within binary, try appended to end, before any
+	// certificate table (an Authenticode signature), which is appended
+	// after it.
+	end := size
+	var dirs []pe.DataDirectory
+	switch hdr := file.OptionalHeader.(type) {
+	case *pe.OptionalHeader32:
+		dirs = hdr.DataDirectory[:hdr.NumberOfRvaAndSizes]
+	case *pe.OptionalHeader64:
+		dirs = hdr.DataDirectory[:hdr.NumberOfRvaAndSizes]
+	}
+	if len(dirs) > pe.IMAGE_DIRECTORY_ENTRY_SECURITY {
+		if cert := dirs[pe.IMAGE_DIRECTORY_ENTRY_SECURITY]; cert.Size != 0 && int64(cert.VirtualAddress) >= max && int64(cert.VirtualAddress) < end {
+			end = int64(cert.VirtualAddress)
+		}
+	}
+	section := io.NewSectionReader(rda, max, end-max)
 	return zip.NewReader(section, section.Size())
 }
 
@@ -106,7 +139,7 @@ func zipExeReaderElf(rda io.ReaderAt, size int64) (*zip.Reader, error) {
 		}
 
 		// Otherwise move end of file pointer
-		end := int64(sect.Offset + sect.Size)
+		end := int64(sect.Offset + sect.FileSize)
 		if end > max {
 			max = end
 		}
@@ -116,3 +149,58 @@ func zipExeReaderElf(rda io.ReaderAt, size int64) (*zip.Reader, error) {
 	section := io.NewSectionReader(rda, max, size-max)
 	return zip.NewReader(section, section.Size())
 }
+
+// exeDataEndPe finds the end of the sections of a PE binary.
+func exeDataEndPe(rda io.ReaderAt) (int64, error) {
+	file, err := pe.NewFile(rda)
+	if err != nil {
+		return 0, err
+	}
+
+	var max int64
+	for _, sec := range file.Sections {
+		if end := int64(sec.Offset + sec.Size); end > max {
+			max = end
+		}
+	}
+	return max, nil
+}
+
+// exeDataEndElf finds the end of the sections, and the section header
+// table (which usually follows them) of an ELF binary.
+func exeDataEndElf(rda io.ReaderAt) (int64, error) {
+	file, err := elf.NewFile(rda)
+	if err != nil {
+		return 0, err
+	}
+
+	var max int64
+	for _, sect := range file.Sections {
+		if sect.Type == elf.SHT_NOBITS {
+			continue
+		}
+		if end := int64(sect.Offset + sect.FileSize); end > max {
+			max = end
+		}
+	}
+
+	// debug/elf doesn't expose the location of the section header
+	// table, so read it from the file header.
+	var hdr [64]byte
+	if _, err := rda.ReadAt(hdr[:], 0); err != nil {
+		return 0, err
+	}
+	var shoff, shentsize, shnum uint64
+	bo := file.ByteOrder
+	if file.Class == elf.ELFCLASS64 {
+		shoff = bo.Uint64(hdr[0x28:])
+		shentsize, shnum = uint64(bo.Uint16(hdr[0x3a:])), uint64(bo.Uint16(hdr[0x3c:]))
+	} else {
+		shoff = uint64(bo.Uint32(hdr[0x20:]))
+		shentsize, shnum = uint64(bo.Uint16(hdr[0x2e:])), uint64(bo.Uint16(hdr[0x30:]))
+	}
+	if end := int64(shoff + shentsize*shnum); end > max {
+		max = end
+	}
+	return max, nil
+}